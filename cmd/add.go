@@ -2,13 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 
 	"github.com/Skarlso/providers-example/pkg/models"
-	"github.com/Skarlso/providers-example/pkg/providers/storer"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/validation"
 )
 
 var (
@@ -18,10 +20,14 @@ var (
 		Run:   runAddCmd,
 	}
 	addArgs struct {
-		_type    string
-		name     string
-		location string
-		image    string
+		_type       string
+		name        string
+		location    string
+		image       string
+		description string
+		owner       string
+		checksum    string
+		labels      []string
 	}
 )
 
@@ -29,41 +35,55 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 	flag := addCmd.Flags()
 	flag.StringVar(&addArgs._type, "type", models.Bare, "--type bare")
-	flag.StringVar(&addArgs.name, "name", "", "--name bare")
-	flag.StringVar(&addArgs.location, "file-location", "", "--file-location ~/.config/providers/")
+	flag.StringVar(&addArgs.name, "name", "", "--name bare or --name bare@1.2.0 to add a version")
+	flag.StringVar(&addArgs.location, "file-location", "", "--file-location ~/.config/providers/, the folder containing a binary named like the plugin, defaults to the location")
 	flag.StringVar(&addArgs.image, "image", "", "--image skarlso/providers:echo-v1")
+	flag.StringVar(&addArgs.description, "description", "", "--description 'Echoes its arguments.'")
+	flag.StringVar(&addArgs.owner, "owner", "", "--owner team-infra")
+	flag.StringVar(&addArgs.checksum, "checksum", "", "--checksum sha256:<hex> pins the binary or image digest")
+	flag.StringArrayVar(&addArgs.labels, "label", nil, "--label team=infra --label tier=prod")
 }
 
 func runAddCmd(cmd *cobra.Command, args []string) {
-	if addArgs.location == "" {
-		addArgs.location = rootArgs.location
-	}
-	out := zerolog.ConsoleWriter{
-		Out: os.Stderr,
-	}
-	log := zerolog.New(out).With().
-		Timestamp().
-		Logger()
+	log := newLogger()
 
-	store, err := storer.NewLiteStorer(log, rootArgs.location)
+	labels, err := providers.ParseLabels(addArgs.labels)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to initialise storer")
+		log.Error().Err(err).Msg("Invalid label")
 		os.Exit(1)
 	}
+	name, version, _ := providers.ParseRef(addArgs.name)
 	plugin := &models.Plugin{
-		Name: addArgs.name,
-		Type: addArgs._type,
+		Name:        name,
+		Version:     version,
+		Type:        addArgs._type,
+		Description: addArgs.description,
+		Owner:       addArgs.owner,
+		Checksum:    addArgs.checksum,
+		Labels:      labels,
 	}
-	if addArgs._type == models.Container {
+	if addArgs._type == models.Container || addArgs.image != "" {
 		plugin.Container = &models.ContainerPlugin{
 			Image: addArgs.image,
 		}
-	} else if addArgs._type == models.Bare {
+	}
+	if addArgs._type == models.Bare || addArgs.location != "" {
+		location := addArgs.location
+		if location == "" {
+			location = rootArgs.location
+			log.Info().Str("location", location).Msg("No --file-location given, looking for the binary in the location.")
+		}
 		plugin.Bare = &models.BareMetalPlugin{
-			Location: addArgs.location,
+			Location: location,
 		}
-	} else {
-		log.Error().Str("type", addArgs._type).Msg("Invalid type.")
+	}
+	if err := validation.Plugin(plugin); err != nil {
+		logInvalid(log, err)
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
 		os.Exit(1)
 	}
 	if err := store.Create(context.Background(), plugin); err != nil {
@@ -71,3 +91,15 @@ func runAddCmd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+// logInvalid logs every problem validation found with a plugin on its own line.
+func logInvalid(log zerolog.Logger, err error) {
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		log.Error().Err(err).Msg("Invalid plugin")
+		return
+	}
+	for _, e := range errs {
+		log.Error().Str("field", e.Field).Msg(e.Message)
+	}
+}
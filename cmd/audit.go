@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var (
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Shows who changed which plugin and when.",
+		Run:   runAuditCmd,
+	}
+	auditArgs struct {
+		name   string
+		since  string
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	flag := auditCmd.Flags()
+	flag.StringVar(&auditArgs.name, "name", "", "--name bob or --name bob@1.2.0")
+	flag.StringVar(&auditArgs.since, "since", "", "--since 24h or --since 2021-11-01 or --since 2021-11-01T10:00:00Z")
+	flag.StringVarP(&auditArgs.output, "output", "o", tableOutput, "--output table|json|yaml")
+}
+
+func runAuditCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch auditArgs.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json or yaml", auditArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	since, err := parseSince(auditArgs.since, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid --since")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	auditor, ok := store.(providers.Auditor)
+	if !ok {
+		log.Error().Str("store", rootArgs.store).Msg("The store doesn't keep an audit log.")
+		os.Exit(1)
+	}
+	events, err := auditor.AuditLog(context.Background(), providers.AuditOpts{Ref: auditArgs.name, Since: since})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read audit log")
+		os.Exit(1)
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	switch auditArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, events)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, events)
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Time", "User", "Action", "Plugin", "Changes"})
+		table.SetAutoWrapText(false)
+		for _, e := range events {
+			ref := e.Name
+			if e.Version != "" {
+				ref = providers.Ref(e.Name, e.Version)
+			}
+			table.Append([]string{formatTime(e.At), e.User, e.Action, ref, strings.Join(auditChanges(e), ", ")})
+		}
+		table.Render()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print audit log")
+		os.Exit(1)
+	}
+}
+
+// parseSince parses a duration before now, a date or a time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration, a date nor an RFC 3339 time", value)
+}
+
+// auditChanges describes the fields an update changed as `field: before -> after`.
+func auditChanges(e *models.AuditEvent) []string {
+	if e.Before == nil || e.After == nil {
+		return nil
+	}
+	b, a := e.Before, e.After
+	var changes []string
+	diff := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field, before, after))
+		}
+	}
+	diff("type", b.Type, a.Type)
+	diff("source", source(b), source(a))
+	diff("description", b.Description, a.Description)
+	diff("owner", b.Owner, a.Owner)
+	diff("labels", providers.FormatLabels(b.Labels), providers.FormatLabels(a.Labels))
+	diff("checksum", b.Checksum, a.Checksum)
+	diff("active", strconv.FormatBool(b.Active), strconv.FormatBool(a.Active))
+	return changes
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Writes a copy of the SQLite database which is safe to take while plugins are used.",
+		Run:   runBackupCmd,
+	}
+	backupArgs struct {
+		out string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	flag := backupCmd.Flags()
+	flag.StringVar(&backupArgs.out, "out", "", "--out provider-backup.db, must not exist yet")
+}
+
+func runBackupCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if backupArgs.out == "" {
+		log.Error().Msg("--out has to be given.")
+		os.Exit(1)
+	}
+	store, err := newLiteStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	if err := store.Backup(context.Background(), backupArgs.out); err != nil {
+		log.Error().Err(err).Msg("Failed to back up database")
+		os.Exit(1)
+	}
+}
+
+// newLiteStorer returns the SQLite storer for commands which only work with it.
+func newLiteStorer(log zerolog.Logger) (*storer.LiteStorer, error) {
+	if rootArgs.store != sqliteStore {
+		return nil, fmt.Errorf("only supported with the %s store, not %q", sqliteStore, rootArgs.store)
+	}
+	l, err := storer.NewLiteStorer(log, rootArgs.location)
+	if err != nil {
+		return nil, err
+	}
+	l.TrashRetention = rootArgs.trashRetention
+	return l, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/catalog"
+	"github.com/Skarlso/providers-example/pkg/providers/tar"
+)
+
+// httpClient downloads catalogs and archives. Its timeout is set from the settings.
+var httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+// catalogSources returns the --catalog sources, or the catalog.yaml in the location if none were given.
+func catalogSources() []string {
+	if len(rootArgs.catalogs) > 0 {
+		return rootArgs.catalogs
+	}
+	return []string{filepath.Join(rootArgs.location, "catalog.yaml")}
+}
+
+func loadCatalog(ctx context.Context) (*catalog.Catalog, error) {
+	return catalog.Load(ctx, httpClient, catalogSources())
+}
+
+// newInstaller returns an installer which extracts bare plugins under the location.
+func newInstaller(log zerolog.Logger, store providers.Storer) *catalog.Installer {
+	return catalog.NewInstaller(catalog.Config{
+		Dir: filepath.Join(rootArgs.location, "plugins"),
+	}, catalog.Dependencies{
+		Logger:   log,
+		Storer:   store,
+		Archiver: tar.NewTarer(log),
+		Client:   httpClient,
+	})
+}
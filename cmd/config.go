@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/config"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Shows and changes the settings in the config file.",
+		Long: `Shows and changes the settings in the config file. Every setting can be overridden with an environment
+variable named like PROVIDERS_<KEY>, with dashes replaced by underscores, and by its flag.
+Settings: ` + strings.Join(config.Keys(), ", ") + `.`,
+	}
+	configViewCmd = &cobra.Command{
+		Use:   "view",
+		Short: "Prints the settings in effect, taking flags, environment and the config file into account.",
+		Args:  cobra.NoArgs,
+		Run:   runConfigViewCmd,
+	}
+	configGetCmd = &cobra.Command{
+		Use:   "get key",
+		Short: "Prints the value in effect of a setting.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runConfigGetCmd,
+	}
+	configSetCmd = &cobra.Command{
+		Use:   "set key value",
+		Short: "Saves a setting in the config file. An empty value removes it. Lists are comma separated.",
+		Args:  cobra.ExactArgs(2),
+		Run:   runConfigSetCmd,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd, configGetCmd, configSetCmd)
+}
+
+func runConfigViewCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	path, err := configPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find config file")
+		os.Exit(1)
+	}
+	fmt.Printf("# config file: %s\n", path)
+	if err := encodeYAML(os.Stdout, effectiveConfig()); err != nil {
+		log.Error().Err(err).Msg("Failed to print config")
+		os.Exit(1)
+	}
+}
+
+func runConfigGetCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	value, err := effectiveConfig().Get(args[0])
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get setting")
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSetCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	path, err := configPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find config file")
+		os.Exit(1)
+	}
+	// only the file is changed, without what the environment or flags override
+	c, err := config.Load(path)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	if err := c.Set(args[0], args[1]); err != nil {
+		log.Error().Err(err).Msg("Invalid setting")
+		os.Exit(1)
+	}
+	if err := c.Save(path); err != nil {
+		log.Error().Err(err).Msg("Failed to save config")
+		os.Exit(1)
+	}
+	log.Info().Str("key", args[0]).Str("path", path).Msg("Saved setting.")
+}
+
+// effectiveConfig returns the settings in effect after loadSettings ran.
+func effectiveConfig() *config.Config {
+	return &config.Config{
+		Location:       rootArgs.location,
+		Store:          rootArgs.store,
+		Catalogs:       catalogSources(),
+		TrashRetention: rootArgs.trashRetention,
+		RunTimeout:     rootArgs.runTimeout,
+		HTTPTimeout:    rootArgs.httpTimeout,
+		LogLevel:       rootArgs.logLevel,
+		LogFormat:      rootArgs.logFormat,
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var (
+	describeCmd = &cobra.Command{
+		Use:   "describe",
+		Short: "Shows everything about a plugin and whether it's ready to run.",
+		Run:   runDescribeCmd,
+	}
+	describeArgs struct {
+		name   string
+		output string
+	}
+)
+
+// description is what describe prints.
+type description struct {
+	Plugin *models.Plugin    `json:"plugin" yaml:"plugin"`
+	Health *providers.Health `json:"health" yaml:"health"`
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	flag := describeCmd.Flags()
+	flag.StringVar(&describeArgs.name, "name", "", "--name bob or --name bob@1.2.0")
+	flag.StringVarP(&describeArgs.output, "output", "o", tableOutput, "--output table|json|yaml|go-template='{{.Health.Available}}'")
+}
+
+func runDescribeCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	print, err := newDescriptionPrinter(describeArgs.output)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	plugin, err := store.Get(context.Background(), describeArgs.name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get plugin")
+		os.Exit(1)
+	}
+	runner, err := newRunner(log, store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create container runner")
+		os.Exit(1)
+	}
+	health, err := runner.Inspect(context.Background(), plugin)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to inspect plugin")
+		os.Exit(1)
+	}
+	if err := print(os.Stdout, &description{Plugin: plugin, Health: health}); err != nil {
+		log.Error().Err(err).Msg("Failed to print plugin")
+		os.Exit(1)
+	}
+}
+
+// newDescriptionPrinter returns the printer for the --output value of describe.
+func newDescriptionPrinter(format string) (func(w io.Writer, d *description) error, error) {
+	switch format {
+	case tableOutput, "":
+		return printDescription, nil
+	case jsonOutput:
+		return func(w io.Writer, d *description) error { return encodeJSON(w, d) }, nil
+	case yamlOutput:
+		return func(w io.Writer, d *description) error { return encodeYAML(w, d) }, nil
+	}
+	if strings.HasPrefix(format, goTemplatePrefix) {
+		tmpl, err := parseTemplate(format)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, d *description) error { return tmpl.Execute(w, d) }, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, must be one of table, json, yaml or go-template=...", format)
+}
+
+// printDescription prints a description as aligned `key: value` lines.
+func printDescription(w io.Writer, d *description) error {
+	p, h := d.Plugin, d.Health
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(key, value string) {
+		fmt.Fprintf(tw, "%s:\t%s\n", key, value)
+	}
+	line("Name", p.Name)
+	line("Version", p.Version)
+	line("Active", strconv.FormatBool(p.Active))
+	line("ID", strconv.Itoa(p.ID))
+	line("Type", p.Type)
+	line("Description", p.Description)
+	line("Owner", p.Owner)
+	line("Labels", providers.FormatLabels(p.Labels))
+	line("Created", formatTime(p.CreatedAt))
+	lastRun := "never"
+	if !p.LastRun.IsZero() {
+		lastRun = fmt.Sprintf("%s (%s ago)", formatTime(p.LastRun), time.Since(p.LastRun).Round(time.Second))
+	}
+	line("Last Run", lastRun)
+	if p.Type == models.Container {
+		line("Image", h.Source)
+	} else {
+		line("Binary", h.Source)
+	}
+	available := "yes"
+	if !h.Available {
+		available = "no, " + h.Problem
+	}
+	line("Available", available)
+	line("Digests", strings.Join(h.Digests, ", "))
+	checksum := h.Checksum
+	if p.Checksum != "" {
+		checksum = p.Checksum + " (" + h.Checksum + ")"
+	}
+	line("Checksum", checksum)
+	return tw.Flush()
+}
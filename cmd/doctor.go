@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/doctor"
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+var (
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the location, the database, Docker and the binaries of bare plugins.",
+		Long: `Checks the location, the database, Docker and the binaries of bare plugins, and reports whether
+each check passed, warned or failed. Exits with 1 if any check failed. The SQLite database is only read,
+an outdated schema is reported instead of migrated.`,
+		Run: runDoctorCmd,
+	}
+	doctorArgs struct {
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVarP(&doctorArgs.output, "output", "o", tableOutput, "--output table|json|yaml")
+}
+
+func runDoctorCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch doctorArgs.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json or yaml", doctorArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	report := &doctor.Report{}
+	report.Add(doctor.Location(rootArgs.location))
+
+	var store providers.Storer
+	if rootArgs.store == sqliteStore {
+		// not created with NewLiteStorer, which would migrate the database
+		db := &storer.LiteStorer{Logger: log, DBLocation: rootArgs.location}
+		report.Add(doctor.DatabaseFile(db.Path()))
+		if _, err := os.Stat(db.Path()); err == nil {
+			schema := doctor.Schema(ctx, db)
+			report.Add(schema, doctor.Integrity(ctx, db))
+			if schema.Status == doctor.Pass {
+				store = db
+			}
+		}
+	} else {
+		s, err := newStorer(log)
+		if err != nil {
+			report.Add(&doctor.Result{Check: "store", Status: doctor.Fail, Message: err.Error()})
+		} else {
+			store = s
+		}
+	}
+
+	var plugins []*models.Plugin
+	if store != nil {
+		var err error
+		plugins, err = store.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+		if err != nil {
+			report.Add(&doctor.Result{Check: "plugins", Status: doctor.Fail, Message: err.Error()})
+		}
+	}
+	runner, err := newRunner(log, store)
+	if err != nil {
+		report.Add(&doctor.Result{Check: "docker", Status: doctor.Fail, Message: err.Error()})
+	} else {
+		report.Add(doctor.DockerDaemon(ctx, runner, hasContainerPlugins(plugins)))
+		report.Add(doctor.BarePlugins(ctx, runner, plugins)...)
+	}
+
+	switch doctorArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, report)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, report)
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Status", "Check", "Message"})
+		table.SetAutoWrapText(false)
+		for _, r := range report.Results {
+			table.Append([]string{strings.ToUpper(string(r.Status)), r.Check, r.Message})
+		}
+		table.Render()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print report")
+		os.Exit(1)
+	}
+	if report.Status() == doctor.Fail {
+		os.Exit(1)
+	}
+}
+
+func hasContainerPlugins(plugins []*models.Plugin) bool {
+	for _, p := range plugins {
+		if p.Type == models.Container {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/providers/registry"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Writes every version of every registered plugin to stdout, to be imported elsewhere.",
+		Run:   runExportCmd,
+	}
+	exportArgs struct {
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	flag := exportCmd.Flags()
+	flag.StringVarP(&exportArgs.output, "output", "o", yamlOutput, "--output yaml|json")
+}
+
+func runExportCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if exportArgs.output != yamlOutput && exportArgs.output != jsonOutput {
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of yaml or json", exportArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	doc, err := registry.Export(context.Background(), store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to export plugins")
+		os.Exit(1)
+	}
+	if exportArgs.output == jsonOutput {
+		err = encodeJSON(os.Stdout, doc)
+	} else {
+		err = encodeYAML(os.Stdout, doc)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to write export")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Skarlso/providers-example/pkg/providers/registry"
+)
+
+var (
+	importCmd = &cobra.Command{
+		Use:   "import file",
+		Short: "Registers the plugins of an export. Use - to read from stdin.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runImportCmd,
+	}
+	importArgs struct {
+		merge   bool
+		replace bool
+		dryRun  bool
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	flag := importCmd.Flags()
+	flag.BoolVar(&importArgs.merge, "merge", false, "--merge creates missing plugins and updates changed ones, the default")
+	flag.BoolVar(&importArgs.replace, "replace", false, "--replace also removes plugins which aren't in the file")
+	flag.BoolVar(&importArgs.dryRun, "dry-run", false, "--dry-run prints the changes without making them")
+}
+
+func runImportCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if importArgs.merge && importArgs.replace {
+		log.Error().Msg("Only one of --merge and --replace can be set.")
+		os.Exit(1)
+	}
+	mode := registry.Merge
+	if importArgs.replace {
+		mode = registry.Replace
+	}
+	doc, err := readDocument(args[0])
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read import")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	changes, err := registry.NewImporter(log, store).Import(context.Background(), doc, mode, importArgs.dryRun)
+	var rollbackErr *registry.RollbackError
+	if errors.As(err, &rollbackErr) {
+		log.Error().Err(err).Msg("Failed to import plugins, and some of the changes already made couldn't be undone")
+		os.Exit(1)
+	} else if err != nil {
+		log.Error().Err(err).Msg("Failed to import plugins, nothing was changed")
+		os.Exit(1)
+	}
+	counts := make(map[registry.Action]int)
+	for _, c := range changes {
+		counts[c.Action]++
+		ref := c.Name
+		if c.Version != "" {
+			ref += "@" + c.Version
+		}
+		fmt.Printf("%s %s\n", c.Action, ref)
+	}
+	log.Info().
+		Bool("dry-run", importArgs.dryRun).
+		Int("created", counts[registry.Created]).
+		Int("updated", counts[registry.Updated]).
+		Int("skipped", counts[registry.Skipped]).
+		Int("deleted", counts[registry.Deleted]).
+		Msg("Import done.")
+}
+
+// readDocument reads an export from a file or from stdin if the file is -.
+func readDocument(file string) (*registry.Document, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	doc := &registry.Document{}
+	// JSON is valid YAML, so this reads both
+	if err := yaml.NewDecoder(r).Decode(doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	return doc, nil
+}
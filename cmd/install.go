@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	installCmd = &cobra.Command{
+		Use:   "install name[@version]",
+		Short: "Installs a plugin from the catalog, the newest release unless a version is given.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runInstallCmd,
+	}
+	installArgs struct {
+		use bool
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(installCmd)
+	flag := installCmd.Flags()
+	flag.BoolVar(&installArgs.use, "use", false, "--use makes the installed version the active one")
+}
+
+func runInstallCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	c, err := loadCatalog(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load catalog")
+		os.Exit(1)
+	}
+	entry, release, err := c.Resolve(args[0])
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find plugin")
+		os.Exit(1)
+	}
+	plugin, err := newInstaller(log, store).Install(context.Background(), entry, release)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to install plugin")
+		os.Exit(1)
+	}
+	if installArgs.use && !plugin.Active {
+		if err := store.Activate(context.Background(), plugin.Name, plugin.Version); err != nil {
+			log.Error().Err(err).Msg("Failed to switch version")
+			os.Exit(1)
+		}
+	}
+	log.Info().Str("name", plugin.Name).Str("version", plugin.Version).Msg("Installed plugin.")
+}
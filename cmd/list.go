@@ -2,15 +2,13 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
-	"github.com/olekukonko/tablewriter"
-	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 
-	"github.com/Skarlso/providers-example/pkg/models"
 	"github.com/Skarlso/providers-example/pkg/providers"
-	"github.com/Skarlso/providers-example/pkg/providers/storer"
 )
 
 var (
@@ -20,7 +18,14 @@ var (
 		Run:   runListCmd,
 	}
 	listArgs struct {
-		_type string
+		_type    string
+		filter   string
+		selector string
+		sort     string
+		limit    int
+		offset   int
+		active   bool
+		output   string
 	}
 )
 
@@ -28,47 +33,69 @@ func init() {
 	rootCmd.AddCommand(listCmd)
 	flag := listCmd.Flags()
 	flag.StringVar(&listArgs._type, "type", "", "--type bare")
+	flag.StringVar(&listArgs.filter, "filter", "", "--filter 'echo*' matches names as a glob, or as a substring without wildcards")
+	flag.StringVar(&listArgs.selector, "selector", "", "--selector team=infra,tier!=prod")
+	flag.StringVar(&listArgs.sort, "sort", string(providers.SortByName), "--sort name|created|last-run, prefix with - to reverse, e.g. -last-run")
+	flag.IntVar(&listArgs.limit, "limit", 0, "--limit 10")
+	flag.IntVar(&listArgs.offset, "offset", 0, "--offset 10")
+	flag.BoolVar(&listArgs.active, "active", false, "--active lists only the active version of every plugin")
+	flag.StringVarP(&listArgs.output, "output", "o", tableOutput, outputUsage)
 }
 
-func runListCmd(cmd *cobra.Command, args []string) {
-	out := zerolog.ConsoleWriter{
-		Out: os.Stderr,
+// parseSort parses the value of a --sort flag.
+func parseSort(value string) (providers.SortField, bool, error) {
+	descending := strings.HasPrefix(value, "-")
+	field := providers.SortField(strings.TrimPrefix(value, "-"))
+	switch field {
+	case providers.SortByID, providers.SortByName, providers.SortByCreated, providers.SortByLastRun:
+		return field, descending, nil
 	}
-	log := zerolog.New(out).With().
-		Timestamp().
-		Logger()
+	return "", false, fmt.Errorf("unknown sort field %q, must be one of name, created, last-run", field)
+}
 
-	store, err := storer.NewLiteStorer(log, rootArgs.location)
+func runListCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if listArgs.limit < 0 || listArgs.offset < 0 {
+		log.Error().Int("limit", listArgs.limit).Int("offset", listArgs.offset).Msg("--limit and --offset can't be negative.")
+		os.Exit(1)
+	}
+	print, err := newPrinter(listArgs.output)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise storer")
 		os.Exit(1)
 	}
+	selector, err := providers.ParseSelector(listArgs.selector)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid selector")
+		os.Exit(1)
+	}
+	sortBy, descending, err := parseSort(listArgs.sort)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid sort")
+		os.Exit(1)
+	}
 	results, err := store.List(context.Background(), providers.ListOpts{
 		TypeFilter: listArgs._type,
+		NameFilter: listArgs.filter,
+		Selector:   selector,
+		ActiveOnly: listArgs.active,
+		SortBy:     sortBy,
+		Descending: descending,
+		Limit:      listArgs.limit,
+		Offset:     listArgs.offset,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to list plugins")
 		os.Exit(1)
 	}
-	data := make([][]string, 0)
-	for _, result := range results {
-		d := []string{
-			result.Name,
-			result.Type,
-		}
-		if result.Type == models.Container {
-			d = append(d, result.Container.Image)
-		} else {
-			d = append(d, result.Bare.Location)
-		}
-		data = append(data, d)
-	}
-
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Name", "Type", "Image/Location"})
-
-	for _, v := range data {
-		table.Append(v)
+	if err := print(os.Stdout, results); err != nil {
+		log.Error().Err(err).Msg("Failed to print plugins")
+		os.Exit(1)
 	}
-	table.Render() // Send output
 }
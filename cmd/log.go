@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	consoleLogFormat = "console"
+	jsonLogFormat    = "json"
+	defaultLogLevel  = "info"
+)
+
+// newLogger returns the logger commands log with. It writes to stderr, in the format given with --log-format.
+// The level is set globally by loadSettings.
+func newLogger() zerolog.Logger {
+	var out io.Writer = os.Stderr
+	if rootArgs.logFormat != jsonLogFormat {
+		out = zerolog.ConsoleWriter{
+			Out: os.Stderr,
+		}
+	}
+	return zerolog.New(out).With().
+		Timestamp().
+		Logger()
+}
+
+// setLogLevel makes every logger log at the given level and above.
+func setLogLevel(value string) error {
+	level, err := zerolog.ParseLevel(value)
+	if err != nil || value == "" {
+		return fmt.Errorf("invalid log level %q, must be one of trace, debug, info, warn, error", value)
+	}
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/providers/catalog"
+)
+
+var (
+	outdatedCmd = &cobra.Command{
+		Use:   "outdated",
+		Short: "Lists installed plugins for which the catalog has a newer version.",
+		Run:   runOutdatedCmd,
+	}
+	outdatedArgs struct {
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+	flag := outdatedCmd.Flags()
+	flag.StringVarP(&outdatedArgs.output, "output", "o", tableOutput, "--output table|json|yaml")
+}
+
+func runOutdatedCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch outdatedArgs.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json or yaml", outdatedArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	c, err := loadCatalog(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load catalog")
+		os.Exit(1)
+	}
+	outdated, err := catalog.FindOutdated(context.Background(), store, c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find outdated plugins")
+		os.Exit(1)
+	}
+	if outdated == nil {
+		outdated = []*catalog.Outdated{}
+	}
+	switch outdatedArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, outdated)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, outdated)
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Current", "Latest"})
+		for _, o := range outdated {
+			table.Append([]string{o.Name, o.Current, o.Latest})
+		}
+		table.Render()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print outdated plugins")
+		os.Exit(1)
+	}
+}
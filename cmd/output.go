@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+const (
+	tableOutput      = "table"
+	wideOutput       = "wide"
+	jsonOutput       = "json"
+	yamlOutput       = "yaml"
+	nameOutput       = "name"
+	goTemplatePrefix = "go-template="
+
+	outputUsage = "--output table|wide|json|yaml|name|go-template='{{.Name}} {{.Type}}{{\"\\n\"}}'"
+)
+
+// printer writes plugins to w in one of the --output formats.
+type printer func(w io.Writer, plugins []*models.Plugin) error
+
+// newPrinter returns the printer for an --output value. It's called before talking to the store,
+// so a typo in the format doesn't cost a query.
+func newPrinter(format string) (printer, error) {
+	switch format {
+	case tableOutput, "":
+		return printTable(false), nil
+	case wideOutput:
+		return printTable(true), nil
+	case jsonOutput:
+		return printJSON, nil
+	case yamlOutput:
+		return printYAML, nil
+	case nameOutput:
+		return printNames, nil
+	}
+	if strings.HasPrefix(format, goTemplatePrefix) {
+		tmpl, err := parseTemplate(format)
+		if err != nil {
+			return nil, err
+		}
+		return printTemplate(tmpl), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, must be one of table, wide, json, yaml, name or go-template=...", format)
+}
+
+func printTable(wide bool) printer {
+	return func(w io.Writer, plugins []*models.Plugin) error {
+		table := tablewriter.NewWriter(w)
+		header := []string{"Name", "Version", "Active", "Type", "Image/Location", "Owner", "Labels", "Description"}
+		if wide {
+			header = append([]string{"ID"}, append(header, "Created", "Last Run")...)
+		}
+		table.SetHeader(header)
+		for _, p := range plugins {
+			active := ""
+			if p.Active {
+				active = "*"
+			}
+			row := []string{p.Name, p.Version, active, p.Type, source(p), p.Owner, providers.FormatLabels(p.Labels), p.Description}
+			if wide {
+				row = append([]string{strconv.Itoa(p.ID)}, append(row, formatTime(p.CreatedAt), formatTime(p.LastRun))...)
+			}
+			table.Append(row)
+		}
+		table.Render()
+		return nil
+	}
+}
+
+func printJSON(w io.Writer, plugins []*models.Plugin) error {
+	if plugins == nil {
+		plugins = []*models.Plugin{}
+	}
+	return encodeJSON(w, plugins)
+}
+
+func printYAML(w io.Writer, plugins []*models.Plugin) error {
+	if plugins == nil {
+		plugins = []*models.Plugin{}
+	}
+	return encodeYAML(w, plugins)
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func encodeYAML(w io.Writer, v interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// printNames prints a reference per plugin, so every version gets its own line which can be passed to the
+// other commands: name@version, or just the name if the plugin has no version.
+func printNames(w io.Writer, plugins []*models.Plugin) error {
+	for _, p := range plugins {
+		ref := p.Name
+		if p.Version != "" {
+			ref = providers.Ref(p.Name, p.Version)
+		}
+		if _, err := fmt.Fprintln(w, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses the template of a go-template=... output format.
+func parseTemplate(format string) (*template.Template, error) {
+	tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, goTemplatePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate executes the template once for every plugin.
+func printTemplate(tmpl *template.Template) printer {
+	return func(w io.Writer, plugins []*models.Plugin) error {
+		for _, p := range plugins {
+			if err := tmpl.Execute(w, p); err != nil {
+				return fmt.Errorf("failed to execute template for %s: %w", p.Name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// source returns the image of a container plugin or the location of a bare plugin.
+func source(p *models.Plugin) string {
+	switch {
+	case p.Container != nil:
+		return p.Container.Image
+	case p.Bare != nil:
+		return p.Bare.Location
+	}
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
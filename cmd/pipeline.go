@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/pipeline"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var (
+	pipelineCmd = &cobra.Command{
+		Use:   "pipeline",
+		Short: "Manages and runs pipelines, which chain plugins like a shell pipeline.",
+		Long: `Manages and runs pipelines. A pipeline runs its steps at the same time, the stdout of every step
+streams into the stdin of the next one, whichever runner runs them. A pipeline is defined in YAML or JSON:
+  name: etl
+  description: extracts, transforms and loads
+  steps:
+  - plugin: extract
+    args: [--since, 24h]
+  - plugin: transform@1.2.0
+  - plugin: load`,
+	}
+	pipelineCreateCmd = &cobra.Command{
+		Use:   "create FILE",
+		Short: "Creates a pipeline from a definition. Use - to read from stdin.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runPipelineCreateCmd,
+	}
+	pipelineListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists pipelines.",
+		Run:   runPipelineListCmd,
+	}
+	pipelineDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Deletes a pipeline. The plugins it runs are left alone.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runPipelineDeleteCmd,
+	}
+	pipelineRunCmd = &cobra.Command{
+		Use:   "run NAME",
+		Short: "Runs a pipeline.",
+		Long: `Runs a pipeline. What is piped into the executor goes to the first step, the output of the last
+step is written to stdout. Once a step fails, the others are stopped. The result of every step is printed
+to stderr, and the executor exits with 1 if a step failed.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runPipelineRunCmd,
+	}
+	pipelineArgs struct {
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(pipelineCmd)
+	pipelineCmd.AddCommand(pipelineCreateCmd, pipelineListCmd, pipelineDeleteCmd, pipelineRunCmd)
+	pipelineListCmd.Flags().StringVarP(&pipelineArgs.output, "output", "o", tableOutput, "--output table|json|yaml")
+	pipelineRunCmd.Flags().DurationVar(&rootArgs.runTimeout, "timeout", defaultRunTimeout, "--timeout 1m, after which the container plugin of a step is killed")
+}
+
+func runPipelineCreateCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	p, err := readPipeline(args[0])
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read pipeline")
+		os.Exit(1)
+	}
+	if err := pipeline.Validate(p); err != nil {
+		log.Error().Err(err).Msg("Invalid pipeline")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	pipelines, err := asPipelineStorer(store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	for i, s := range p.Steps {
+		if _, err := store.Get(ctx, s.Plugin); err != nil {
+			log.Error().Err(err).Int("step", i+1).Str("plugin", s.Plugin).Msg("Failed to find the plugin of a step")
+			os.Exit(1)
+		}
+	}
+	if err := pipelines.CreatePipeline(ctx, p); err != nil {
+		log.Error().Err(err).Msg("Failed to create pipeline")
+		os.Exit(1)
+	}
+	log.Info().Str("name", p.Name).Int("steps", len(p.Steps)).Msg("Pipeline created.")
+}
+
+func runPipelineListCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch pipelineArgs.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json or yaml", pipelineArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	pipelines, err := newPipelineStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	list, err := pipelines.ListPipelines(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list pipelines")
+		os.Exit(1)
+	}
+	if list == nil {
+		list = []*models.Pipeline{}
+	}
+	switch pipelineArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, list)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, list)
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Steps", "Description", "Created"})
+		table.SetAutoWrapText(false)
+		for _, p := range list {
+			steps := make([]string, 0, len(p.Steps))
+			for _, s := range p.Steps {
+				steps = append(steps, s.Plugin)
+			}
+			table.Append([]string{p.Name, strings.Join(steps, " | "), p.Description, formatTime(p.CreatedAt)})
+		}
+		table.Render()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print pipelines")
+		os.Exit(1)
+	}
+}
+
+func runPipelineDeleteCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	pipelines, err := newPipelineStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	if err := pipelines.DeletePipeline(context.Background(), args[0]); err != nil {
+		log.Error().Err(err).Msg("Failed to delete pipeline")
+		os.Exit(1)
+	}
+	log.Info().Str("name", args[0]).Msg("Pipeline deleted.")
+}
+
+func runPipelineRunCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	pipelines, err := asPipelineStorer(store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	p, err := pipelines.GetPipeline(ctx, args[0])
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pipeline")
+		os.Exit(1)
+	}
+	executor := pipeline.NewExecutor(pipeline.Dependencies{
+		Logger: log,
+		NewRunner: func(stdin io.Reader, stdout io.Writer) (providers.Runner, error) {
+			return newRunnerWithIO(log, store, stdin, stdout, nil, false)
+		},
+	})
+	results, err := executor.Run(ctx, p, pipedStdin(), os.Stdout)
+	for _, r := range results {
+		if recordErr := store.RecordRun(ctx, r.Plugin, r.StartedAt); recordErr != nil {
+			log.Debug().Err(recordErr).Str("plugin", r.Plugin).Msg("Failed to record run")
+		}
+	}
+	// stdout is the output of the last step, so the results go to stderr
+	printStepResults(os.Stderr, results)
+	if err != nil {
+		log.Error().Err(err).Msg("Pipeline failed")
+		os.Exit(1)
+	}
+	log.Info().Msg("All done.")
+}
+
+// newPipelineStorer returns the store selected with --store if it keeps pipelines.
+func newPipelineStorer(log zerolog.Logger) (providers.PipelineStorer, error) {
+	store, err := newStorer(log)
+	if err != nil {
+		return nil, err
+	}
+	return asPipelineStorer(store)
+}
+
+func asPipelineStorer(store providers.Storer) (providers.PipelineStorer, error) {
+	pipelines, ok := store.(providers.PipelineStorer)
+	if !ok {
+		return nil, fmt.Errorf("the %s store doesn't keep pipelines", rootArgs.store)
+	}
+	return pipelines, nil
+}
+
+// readPipeline reads the definition of a pipeline from a file or from stdin if the file is -.
+func readPipeline(file string) (*models.Pipeline, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	p := &models.Pipeline{}
+	// JSON is valid YAML, so this reads both
+	if err := yaml.NewDecoder(r).Decode(p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("failed to parse file: it is empty")
+		}
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	return p, nil
+}
+
+func printStepResults(w io.Writer, results []*pipeline.StepResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Step", "Plugin", "Status", "Duration", "Error"})
+	table.SetAutoWrapText(false)
+	for _, r := range results {
+		table.Append([]string{fmt.Sprint(r.Step), r.Plugin, strings.ToUpper(string(r.Status)), r.Duration.Round(time.Millisecond).String(), r.Error})
+	}
+	table.Render()
+}
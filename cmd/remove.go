@@ -4,44 +4,71 @@ import (
 	"context"
 	"os"
 
-	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 
-	"github.com/Skarlso/providers-example/pkg/providers/storer"
+	"github.com/Skarlso/providers-example/pkg/providers"
 )
 
 var (
 	removeCmd = &cobra.Command{
-		Use:   "remove",
-		Short: "Remove a registered plugin.",
-		Run:   runRemoveCmd,
+		Use:   "remove [name[@version]]",
+		Short: "Remove a registered plugin, or every plugin matching a selector.",
+		Long: `Remove a registered plugin, or every plugin matching a selector.
+A plain name removes every version of a plugin, name@version removes a single one.
+Removed plugins are moved to the trash, see the trash command.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  runRemoveCmd,
 	}
 	removeArgs struct {
-		name string
+		name     string
+		selector string
 	}
 )
 
 func init() {
 	rootCmd.AddCommand(removeCmd)
 	flag := removeCmd.Flags()
-	flag.StringVar(&removeArgs.name, "name", "", "--name bare")
+	flag.StringVar(&removeArgs.name, "name", "", "--name bare or --name bare@1.1.0")
+	flag.StringVar(&removeArgs.selector, "selector", "", "--selector team=infra removes every matching plugin")
 }
 
 func runRemoveCmd(cmd *cobra.Command, args []string) {
-	out := zerolog.ConsoleWriter{
-		Out: os.Stderr,
-	}
-	log := zerolog.New(out).With().
-		Timestamp().
-		Logger()
+	log := newLogger()
 
-	store, err := storer.NewLiteStorer(log, rootArgs.location)
+	if len(args) == 1 {
+		if removeArgs.name != "" {
+			log.Error().Msg("The plugin can be given either as an argument or with --name.")
+			os.Exit(1)
+		}
+		removeArgs.name = args[0]
+	}
+	if removeArgs.name != "" && removeArgs.selector != "" {
+		log.Error().Msg("Only one of --name or --selector can be set.")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise storer")
 		os.Exit(1)
 	}
-	if err := store.Delete(context.Background(), removeArgs.name); err != nil {
-		log.Error().Err(err).Msg("Failed to remove plugin")
+	if removeArgs.selector == "" {
+		if err := store.Delete(context.Background(), removeArgs.name); err != nil {
+			log.Error().Err(err).Msg("Failed to remove plugin")
+			os.Exit(1)
+		}
+		return
+	}
+	plugins, err := selectPlugins(context.Background(), store, "", removeArgs.selector)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find plugins")
 		os.Exit(1)
 	}
+	for _, plugin := range plugins {
+		// only remove the versions which matched
+		if err := store.Delete(context.Background(), providers.Ref(plugin.Name, plugin.Version)); err != nil {
+			log.Error().Err(err).Str("name", plugin.Name).Msg("Failed to remove plugin")
+			os.Exit(1)
+		}
+	}
+	log.Info().Int("count", len(plugins)).Msg("Removed plugins.")
 }
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Brings back a removed plugin, or replaces the SQLite database with a backup.",
+		Run:   runRestoreCmd,
+	}
+	restoreArgs struct {
+		from string
+		name string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	flag := restoreCmd.Flags()
+	flag.StringVar(&restoreArgs.from, "from", "", "--from provider-backup.db")
+	flag.StringVar(&restoreArgs.name, "name", "", "--name bob restores every removed version of bob, --name bob@1.2.0 a single one")
+}
+
+func runRestoreCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if (restoreArgs.from == "") == (restoreArgs.name == "") {
+		log.Error().Msg("Exactly one of --from or --name has to be given.")
+		os.Exit(1)
+	}
+	if restoreArgs.name != "" {
+		trash, err := newTrasher(log)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to initialise storer")
+			os.Exit(1)
+		}
+		if err := trash.RestoreTrashed(context.Background(), restoreArgs.name); err != nil {
+			log.Error().Err(err).Msg("Failed to restore plugin")
+			os.Exit(1)
+		}
+		return
+	}
+	store, err := newLiteStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	if err := store.Restore(context.Background(), restoreArgs.from); err != nil {
+		log.Error().Err(err).Msg("Failed to restore database")
+		os.Exit(1)
+	}
+}
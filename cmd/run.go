@@ -2,66 +2,302 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/moby/term"
+	"github.com/olekukonko/tablewriter"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 
+	"github.com/Skarlso/providers-example/pkg/fanout"
+	"github.com/Skarlso/providers-example/pkg/providers"
 	"github.com/Skarlso/providers-example/pkg/providers/bare"
 	"github.com/Skarlso/providers-example/pkg/providers/container"
-	"github.com/Skarlso/providers-example/pkg/providers/storer"
 )
 
 var (
 	runCmd = &cobra.Command{
-		Use:   "run",
-		Short: "Run a plugin.",
-		Run:   runRunCmd,
+		Use:   "run [NAME] [-- ARGS...]",
+		Short: "Run a plugin, or several side by side.",
+		Long: `Run a plugin. Everything after -- is passed to the plugin as it is, one argument each:
+  executor run bob -- echo 'this, and that'
+A bare plugin gets the arguments after its binary, a container plugin gets them as the command of its
+image. Without any, the default command of the image runs.
+Use -it for interactive plugins. Containers then get a terminal and bare plugins run attached to the
+terminal of the executor. --timeout only applies to them if it's given.
+Several plugins are run at the same time with --name a --name b or with --selector, each gets the same
+arguments. Their output is prefixed with their name and a summary is printed once all are done.`,
+		Run: runRunCmd,
 	}
 	runArgs struct {
-		name string
-		args []string
+		names       []string
+		selector    string
+		parallel    int
+		args        []string
+		dryRun      bool
+		output      string
+		interactive bool
+		tty         bool
 	}
 )
 
 func init() {
 	rootCmd.AddCommand(runCmd)
 	flag := runCmd.Flags()
-	flag.StringVar(&runArgs.name, "name", "", "--name")
-	flag.StringSliceVar(&runArgs.args, "args", nil, "--args")
+	flag.StringArrayVar(&runArgs.names, "name", nil, "--name bob, or --name bob@1.2.0 to run a version which isn't active, repeat it to run several")
+	flag.StringVar(&runArgs.selector, "selector", "", "--selector team=infra runs the active version of every matching plugin")
+	flag.IntVar(&runArgs.parallel, "parallel", defaultParallel, "--parallel 8, the most plugins which run at once")
+	flag.StringSliceVar(&runArgs.args, "args", nil, "--args a,b, split at commas, prefer passing arguments after --")
+	flag.DurationVar(&rootArgs.runTimeout, "timeout", defaultRunTimeout, "--timeout 1m, after which a container plugin is killed")
+	flag.BoolVar(&runArgs.dryRun, "dry-run", false, "--dry-run prints what would be run, without running anything")
+	flag.StringVarP(&runArgs.output, "output", "o", yamlOutput, "--output yaml|json, the format --dry-run prints in")
+	flag.BoolVarP(&runArgs.interactive, "interactive", "i", false, "-i forwards stdin to the plugin even if it's a terminal")
+	flag.BoolVarP(&runArgs.tty, "tty", "t", false, "-t attaches the plugin to the terminal, use -it for plugins which prompt")
 }
 
 func runRunCmd(cmd *cobra.Command, args []string) {
-	out := zerolog.ConsoleWriter{
-		Out: os.Stderr,
+	log := newLogger()
+
+	positional := args
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if len(runArgs.args) > 0 {
+			log.Error().Msg("Arguments can be given either after -- or with --args.")
+			os.Exit(1)
+		}
+		positional, runArgs.args = args[:dash], args[dash:]
+	}
+	if len(positional) > 1 {
+		log.Error().Strs("args", positional[1:]).Msg("Only a single plugin can be given as an argument, pass arguments to it after --.")
+		os.Exit(1)
+	}
+	if len(positional) == 1 {
+		if len(runArgs.names) > 0 {
+			log.Error().Msg("The plugin can be given either as an argument or with --name.")
+			os.Exit(1)
+		}
+		runArgs.names = positional
+	}
+	if len(runArgs.names) > 0 && runArgs.selector != "" {
+		log.Error().Msg("Only one of --name or --selector can be set.")
+		os.Exit(1)
+	}
+	if len(runArgs.names) == 0 && runArgs.selector == "" {
+		log.Error().Msg("A plugin has to be given as an argument, with --name or with --selector.")
+		os.Exit(1)
+	}
+	// a selector runs the plugins side by side even if it matches just one
+	fanOut := len(runArgs.names) > 1 || runArgs.selector != ""
+	if fanOut && (runArgs.tty || runArgs.interactive) {
+		log.Error().Msg("-i and -t can only be used with a single plugin.")
+		os.Exit(1)
+	}
+	if runArgs.parallel < 1 {
+		log.Error().Int("parallel", runArgs.parallel).Msg("--parallel has to be at least 1.")
+		os.Exit(1)
 	}
-	log := zerolog.New(out).With().
-		Timestamp().
-		Logger()
 
-	store, err := storer.NewLiteStorer(log, rootArgs.location)
+	if runArgs.tty {
+		if !isTerminal(os.Stdout) || runArgs.interactive && !isTerminal(os.Stdin) {
+			log.Error().Msg("-t needs a terminal, stdin and stdout must not be redirected.")
+			os.Exit(1)
+		}
+		// someone is at the prompt, only a timeout which was asked for applies
+		if !rootArgs.runTimeoutSet {
+			rootArgs.runTimeout = 0
+		}
+	}
+
+	var encode func(w io.Writer, v interface{}) error
+	if runArgs.dryRun {
+		var err error
+		if encode, err = newPlanEncoder(runArgs.output); err != nil {
+			log.Error().Err(err).Msg("Invalid output format")
+			os.Exit(1)
+		}
+	}
+	store, err := newStorer(log)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise storer")
 		os.Exit(1)
 	}
-	barePlugin := bare.NewBareRunner(bare.Config{}, bare.Dependencies{
+	names := runArgs.names
+	if runArgs.selector != "" {
+		if names, err = selectActivePlugins(context.Background(), store, runArgs.selector); err != nil {
+			log.Error().Err(err).Msg("Failed to find plugins")
+			os.Exit(1)
+		}
+	}
+	if runArgs.dryRun {
+		planRun(log, store, names, fanOut, encode)
+		return
+	}
+	if fanOut {
+		runPlugins(log, store, names)
+		return
+	}
+	containerPlugin, err := newRunner(log, store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create container runner")
+		os.Exit(1)
+	}
+	start := time.Now()
+	err = containerPlugin.Run(context.Background(), names[0], runArgs.args)
+	if recordErr := store.RecordRun(context.Background(), names[0], start); recordErr != nil {
+		log.Debug().Err(recordErr).Msg("Failed to record run")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to run plugin")
+		os.Exit(1)
+	}
+	log.Info().Msg("All done.")
+}
+
+// planRun prints the plan of every plugin instead of running them. A list is printed if several would run.
+func planRun(log zerolog.Logger, store providers.Storer, names []string, list bool, encode func(w io.Writer, v interface{}) error) {
+	containerPlugin, err := newRunner(log, store)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create container runner")
+		os.Exit(1)
+	}
+	plans := make([]*providers.Plan, 0, len(names))
+	for _, name := range names {
+		plan, err := containerPlugin.Plan(context.Background(), name, runArgs.args)
+		if err != nil {
+			log.Error().Err(err).Str("name", name).Msg("Failed to plan run")
+			os.Exit(1)
+		}
+		plans = append(plans, plan)
+	}
+	var v interface{} = plans
+	if !list {
+		v = plans[0]
+	}
+	if err := encode(os.Stdout, v); err != nil {
+		log.Error().Err(err).Msg("Failed to print plan")
+		os.Exit(1)
+	}
+}
+
+// runPlugins runs several plugins side by side, at most --parallel at once, and prints a summary of how
+// each of them did. Exits with 1 if any of them failed.
+func runPlugins(log zerolog.Logger, store providers.Storer, names []string) {
+	executor := fanout.NewExecutor(fanout.Config{
+		Parallel: runArgs.parallel,
+	}, fanout.Dependencies{
+		Logger: log,
+		NewRunner: func(stdout, stderr io.Writer) (providers.Runner, error) {
+			return newRunnerWithIO(log, store, nil, stdout, stderr, false)
+		},
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	ctx := context.Background()
+	results, err := executor.Run(ctx, names, runArgs.args)
+	for _, r := range results {
+		if recordErr := store.RecordRun(ctx, r.Plugin, r.StartedAt); recordErr != nil {
+			log.Debug().Err(recordErr).Str("plugin", r.Plugin).Msg("Failed to record run")
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Plugin", "Status", "Duration", "Exit Code", "Error"})
+	table.SetAutoWrapText(false)
+	for _, r := range results {
+		code := ""
+		if r.ExitCode >= 0 {
+			code = strconv.Itoa(r.ExitCode)
+		}
+		table.Append([]string{r.Plugin, strings.ToUpper(string(r.Status)), r.Duration.Round(time.Millisecond).String(), code, r.Error})
+	}
+	table.Render()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to run plugins")
+		os.Exit(1)
+	}
+	log.Info().Msg("All done.")
+}
+
+// selectActivePlugins returns the names of the plugins matching the selector. Only their active versions
+// are run, so every plugin is run once.
+func selectActivePlugins(ctx context.Context, store providers.Storer, selector string) ([]string, error) {
+	s, err := providers.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := store.List(ctx, providers.ListOpts{Selector: s, ActiveOnly: true, SortBy: providers.SortByName})
+	if err != nil {
+		return nil, err
+	}
+	if len(plugins) == 0 {
+		return nil, fmt.Errorf("no plugins match %q", selector)
+	}
+	names := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// newPlanEncoder returns the encoder for the --output value of run --dry-run.
+func newPlanEncoder(format string) (func(w io.Writer, v interface{}) error, error) {
+	switch format {
+	case yamlOutput:
+		return encodeYAML, nil
+	case jsonOutput:
+		return encodeJSON, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, must be one of yaml or json", format)
+}
+
+// newRunner returns the chain of runners plugins are run by. Container plugins are run by the first one,
+// everything else is handed to the bare runner.
+func newRunner(log zerolog.Logger, store providers.Storer) (*container.Runner, error) {
+	stdin := pipedStdin()
+	if runArgs.interactive {
+		stdin = os.Stdin
+	}
+	return newRunnerWithIO(log, store, stdin, nil, nil, runArgs.tty)
+}
+
+// newRunnerWithIO returns the chain of runners for a plugin which reads stdin and writes to stdout and
+// stderr as it runs. A nil stdout prints the output once the plugin is done, a nil stderr is the executor's.
+func newRunnerWithIO(log zerolog.Logger, store providers.Storer, stdin io.Reader, stdout, stderr io.Writer, tty bool) (*container.Runner, error) {
+	barePlugin := bare.NewBareRunner(bare.Config{
+		TTY: tty,
+	}, bare.Dependencies{
 		Logger: log,
 		Storer: store,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
 	})
-	containerPlugin, err := container.NewRunner(container.Config{
-		DefaultMaximumCommandRuntime: 15,
+	return container.NewRunner(container.Config{
+		DefaultMaximumCommandRuntime: rootArgs.runTimeout,
+		TTY:                          tty,
 	}, container.Dependencies{
 		Storer: store,
 		Next:   barePlugin,
 		Logger: log,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
 	})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to create container runner")
-		os.Exit(1)
-	}
-	if err := containerPlugin.Run(context.Background(), runArgs.name, runArgs.args); err != nil {
-		log.Error().Err(err).Msg("Failed to run plugin")
-		os.Exit(1)
+}
+
+// pipedStdin returns stdin if something is piped into the executor, so plugins can be used as filters.
+// A terminal isn't forwarded.
+func pipedStdin() io.Reader {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return nil
 	}
-	log.Info().Msg("All done.")
+	return os.Stdin
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd())
 }
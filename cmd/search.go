@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/catalog"
+)
+
+var (
+	searchCmd = &cobra.Command{
+		Use:   "search [term]",
+		Short: "Searches the catalog for plugins whose name or description contains the term.",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runSearchCmd,
+	}
+	searchArgs struct {
+		output string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	flag := searchCmd.Flags()
+	flag.StringVarP(&searchArgs.output, "output", "o", tableOutput, "--output table|json|yaml|name")
+}
+
+func runSearchCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch searchArgs.output {
+	case tableOutput, jsonOutput, yamlOutput, nameOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json, yaml or name", searchArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	term := ""
+	if len(args) == 1 {
+		term = args[0]
+	}
+	c, err := loadCatalog(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load catalog")
+		os.Exit(1)
+	}
+	entries := c.Search(term)
+	if entries == nil {
+		entries = []*catalog.Entry{}
+	}
+	switch searchArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, entries)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, entries)
+	case nameOutput:
+		for _, e := range entries {
+			fmt.Println(e.Name)
+		}
+	default:
+		err = printSearchTable(log, os.Stdout, entries)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print plugins")
+		os.Exit(1)
+	}
+}
+
+// printSearchTable prints the entries together with the version of them which is installed, if any.
+func printSearchTable(log zerolog.Logger, w io.Writer, entries []*catalog.Entry) error {
+	store, err := newStorer(log)
+	if err != nil {
+		return fmt.Errorf("failed to initialise storer: %w", err)
+	}
+	installed, err := store.List(context.Background(), providers.ListOpts{ActiveOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to list installed plugins: %w", err)
+	}
+	versions := make(map[string]string, len(installed))
+	for _, p := range installed {
+		versions[p.Name] = p.Version
+		if p.Version == "" {
+			versions[p.Name] = "yes"
+		}
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Name", "Type", "Latest", "Installed", "Description"})
+	for _, e := range entries {
+		latest := ""
+		if r, err := e.Latest(""); err == nil && r != nil {
+			latest = r.Version
+		}
+		table.Append([]string{e.Name, e.Type, latest, versions[e.Name], e.Description})
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/memory"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+const (
+	sqliteStore = "sqlite"
+	memoryStore = "memory"
+)
+
+// newStorer creates the storer selected with --store.
+func newStorer(log zerolog.Logger) (providers.Storer, error) {
+	switch store := rootArgs.store; {
+	case store == sqliteStore:
+		l, err := storer.NewLiteStorer(log, rootArgs.location)
+		if err != nil {
+			return nil, err
+		}
+		l.TrashRetention = rootArgs.trashRetention
+		return l, nil
+	case store == memoryStore:
+		m := memory.NewStorer(log)
+		m.TrashRetention = rootArgs.trashRetention
+		return m, nil
+	case strings.HasPrefix(store, "postgres://"), strings.HasPrefix(store, "postgresql://"):
+		p, err := storer.NewPostgresStorer(log, store)
+		if err != nil {
+			return nil, err
+		}
+		p.TrashRetention = rootArgs.trashRetention
+		return p, nil
+	case strings.HasPrefix(store, "file://"):
+		f, err := storer.NewFileStorer(log, strings.TrimPrefix(store, "file://"))
+		if err != nil {
+			return nil, err
+		}
+		f.TrashRetention = rootArgs.trashRetention
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unknown store %q", store)
+	}
+}
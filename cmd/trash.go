@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var (
+	trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "Manages removed plugins, which can be restored with restore --name until they expire.",
+	}
+	trashListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists removed plugins.",
+		Run:   runTrashListCmd,
+	}
+	trashPurgeCmd = &cobra.Command{
+		Use:   "purge",
+		Short: "Deletes removed plugins for good.",
+		Run:   runTrashPurgeCmd,
+	}
+	trashArgs struct {
+		output    string
+		olderThan time.Duration
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd, trashPurgeCmd)
+	trashListCmd.Flags().StringVarP(&trashArgs.output, "output", "o", tableOutput, "--output table|json|yaml")
+	trashPurgeCmd.Flags().DurationVar(&trashArgs.olderThan, "older-than", 0, "--older-than 24h only purges plugins removed before then")
+}
+
+func runTrashListCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	switch trashArgs.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		log.Error().Err(fmt.Errorf("unknown output format %q, must be one of table, json or yaml", trashArgs.output)).Msg("Invalid output format")
+		os.Exit(1)
+	}
+	trash, err := newTrasher(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	plugins, err := trash.ListTrash(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trash")
+		os.Exit(1)
+	}
+	if plugins == nil {
+		plugins = []*models.TrashedPlugin{}
+	}
+	switch trashArgs.output {
+	case jsonOutput:
+		err = encodeJSON(os.Stdout, plugins)
+	case yamlOutput:
+		err = encodeYAML(os.Stdout, plugins)
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Version", "Type", "Image/Location", "Removed", "Expires"})
+		for _, t := range plugins {
+			p := t.Plugin
+			table.Append([]string{p.Name, p.Version, p.Type, source(p), formatTime(t.DeletedAt), formatTime(t.ExpiresAt)})
+		}
+		table.Render()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to print trash")
+		os.Exit(1)
+	}
+}
+
+func runTrashPurgeCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	trash, err := newTrasher(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	// a second into the future, so everything removed up to now is purged
+	before := time.Now().Add(time.Second)
+	if trashArgs.olderThan > 0 {
+		before = time.Now().Add(-trashArgs.olderThan)
+	}
+	n, err := trash.PurgeTrash(context.Background(), before)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge trash")
+		os.Exit(1)
+	}
+	log.Info().Int("count", n).Msg("Purged plugins.")
+}
+
+// newTrasher returns the store selected with --store if it keeps a trash.
+func newTrasher(log zerolog.Logger) (providers.Trasher, error) {
+	store, err := newStorer(log)
+	if err != nil {
+		return nil, err
+	}
+	trash, ok := store.(providers.Trasher)
+	if !ok {
+		return nil, fmt.Errorf("the %s store doesn't keep removed plugins", rootArgs.store)
+	}
+	return trash, nil
+}
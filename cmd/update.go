@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/validation"
+)
+
+var (
+	updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "Updates the details of one plugin, or of every plugin matching a selector.",
+		Run:   runUpdateCmd,
+	}
+	updateArgs struct {
+		name         string
+		selector     string
+		description  string
+		owner        string
+		checksum     string
+		image        string
+		location     string
+		labels       []string
+		removeLabels []string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	flag := updateCmd.Flags()
+	flag.StringVar(&updateArgs.name, "name", "", "--name bare")
+	flag.StringVar(&updateArgs.selector, "selector", "", "--selector team=infra updates every matching plugin")
+	flag.StringVar(&updateArgs.description, "description", "", "--description 'Echoes its arguments.'")
+	flag.StringVar(&updateArgs.owner, "owner", "", "--owner team-infra")
+	flag.StringVar(&updateArgs.checksum, "checksum", "", "--checksum sha256:<hex>, empty to unpin")
+	flag.StringVar(&updateArgs.image, "image", "", "--image skarlso/providers:echo-v2")
+	flag.StringVar(&updateArgs.location, "file-location", "", "--file-location ~/.config/providers/")
+	flag.StringArrayVar(&updateArgs.labels, "label", nil, "--label tier=prod adds or replaces a label")
+	flag.StringArrayVar(&updateArgs.removeLabels, "remove-label", nil, "--remove-label tier")
+}
+
+func runUpdateCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	if (updateArgs.name == "") == (updateArgs.selector == "") {
+		log.Error().Msg("Exactly one of --name or --selector has to be set.")
+		os.Exit(1)
+	}
+	labels, err := providers.ParseLabels(updateArgs.labels)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid label")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	plugins, err := selectPlugins(context.Background(), store, updateArgs.name, updateArgs.selector)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find plugins")
+		os.Exit(1)
+	}
+	flags := cmd.Flags()
+	for _, plugin := range plugins {
+		if flags.Changed("description") {
+			plugin.Description = updateArgs.description
+		}
+		if flags.Changed("owner") {
+			plugin.Owner = updateArgs.owner
+		}
+		if flags.Changed("checksum") {
+			plugin.Checksum = updateArgs.checksum
+		}
+		if flags.Changed("image") {
+			plugin.Type = models.Container
+			plugin.Bare = nil
+			plugin.Container = &models.ContainerPlugin{
+				Image: updateArgs.image,
+			}
+		}
+		if flags.Changed("file-location") {
+			plugin.Type = models.Bare
+			plugin.Container = nil
+			plugin.Bare = &models.BareMetalPlugin{
+				Location: updateArgs.location,
+			}
+		}
+		if plugin.Labels == nil {
+			plugin.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			plugin.Labels[k] = v
+		}
+		for _, k := range updateArgs.removeLabels {
+			delete(plugin.Labels, k)
+		}
+		// stored binaries might have gone missing since, only a new location has to point to one
+		validate := validation.Definition
+		if flags.Changed("file-location") {
+			validate = validation.Plugin
+		}
+		if err := validate(plugin); err != nil {
+			log.Error().Str("name", providers.Ref(plugin.Name, plugin.Version)).Msg("Not updating any plugin.")
+			logInvalid(log, err)
+			os.Exit(1)
+		}
+	}
+	for _, plugin := range plugins {
+		if err := store.Update(context.Background(), plugin); err != nil {
+			log.Error().Err(err).Str("name", plugin.Name).Msg("Failed to update plugin")
+			os.Exit(1)
+		}
+	}
+	log.Info().Int("count", len(plugins)).Msg("Updated plugins.")
+}
+
+// selectPlugins returns the plugin with the given name, or all plugins matching the selector.
+func selectPlugins(ctx context.Context, store providers.Storer, name, selector string) ([]*models.Plugin, error) {
+	if name != "" {
+		plugin, err := store.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Plugin{plugin}, nil
+	}
+	s, err := providers.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return store.List(ctx, providers.ListOpts{Selector: s})
+}
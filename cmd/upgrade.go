@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeCmd = &cobra.Command{
+		Use:   "upgrade name",
+		Short: "Installs and switches to the newest version of a plugin in the catalog.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runUpgradeCmd,
+	}
+	upgradeArgs struct {
+		to string
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	flag := upgradeCmd.Flags()
+	flag.StringVar(&upgradeArgs.to, "to", "", "--to '~1.2' picks the newest version matching the constraint")
+}
+
+func runUpgradeCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	c, err := loadCatalog(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load catalog")
+		os.Exit(1)
+	}
+	plugin, changed, err := newInstaller(log, store).Upgrade(context.Background(), c, args[0], upgradeArgs.to)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to upgrade plugin")
+		os.Exit(1)
+	}
+	if !changed {
+		log.Info().Str("name", plugin.Name).Str("version", plugin.Version).Msg("Already up to date.")
+		return
+	}
+	log.Info().Str("name", plugin.Name).Str("version", plugin.Version).Msg("Upgraded plugin.")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use name@version",
+	Short: "Switches the version of a plugin which is run when no version is given.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runUseCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
+
+func runUseCmd(cmd *cobra.Command, args []string) {
+	log := newLogger()
+
+	name, version, pinned := providers.ParseRef(args[0])
+	if !pinned {
+		log.Error().Str("plugin", args[0]).Msg("A version has to be given as name@version.")
+		os.Exit(1)
+	}
+	store, err := newStorer(log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialise storer")
+		os.Exit(1)
+	}
+	if err := store.Activate(context.Background(), name, version); err != nil {
+		log.Error().Err(err).Msg("Failed to switch version")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,232 @@
+// Package config reads and writes the settings of the executor. Settings come from, in order of precedence,
+// command line flags, PROVIDERS_* environment variables, the config file and built-in defaults. This package
+// deals with the environment and the file, the commands apply their flags on top.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables which override settings, like PROVIDERS_STORE.
+const EnvPrefix = "PROVIDERS_"
+
+// Config holds the settings which can be given in the config file or the environment. Fields which aren't
+// set are empty, and left out of the file.
+type Config struct {
+	// Location is the folder the SQLite database, the default catalog and installed plugins are kept in.
+	Location string `yaml:"location,omitempty"`
+	// Store selects the storer, see the --store flag.
+	Store string `yaml:"store,omitempty"`
+	// Catalogs are the catalog sources.
+	Catalogs []string `yaml:"catalogs,omitempty"`
+	// TrashRetention is how long removed plugins can be restored for.
+	TrashRetention time.Duration `yaml:"trash-retention,omitempty"`
+	// RunTimeout is how long a container plugin may run before it is killed.
+	RunTimeout time.Duration `yaml:"run-timeout,omitempty"`
+	// HTTPTimeout limits downloading catalogs and archives.
+	HTTPTimeout time.Duration `yaml:"http-timeout,omitempty"`
+	// LogLevel is the minimum level of log messages which are printed.
+	LogLevel string `yaml:"log-level,omitempty"`
+	// LogFormat is either console or json.
+	LogFormat string `yaml:"log-format,omitempty"`
+}
+
+// setting reads and writes a single field of Config as a string. Setting an empty string unsets it.
+type setting struct {
+	key string
+	get func(c *Config) string
+	set func(c *Config, value string) error
+}
+
+var settings = []setting{
+	stringSetting("location", func(c *Config) *string { return &c.Location }),
+	stringSetting("store", func(c *Config) *string { return &c.Store }),
+	{
+		key: "catalogs",
+		get: func(c *Config) string { return strings.Join(c.Catalogs, ",") },
+		set: func(c *Config, value string) error {
+			c.Catalogs = nil
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					c.Catalogs = append(c.Catalogs, s)
+				}
+			}
+			return nil
+		},
+	},
+	durationSetting("trash-retention", func(c *Config) *time.Duration { return &c.TrashRetention }),
+	durationSetting("run-timeout", func(c *Config) *time.Duration { return &c.RunTimeout }),
+	durationSetting("http-timeout", func(c *Config) *time.Duration { return &c.HTTPTimeout }),
+	{
+		key: "log-level",
+		get: func(c *Config) string { return c.LogLevel },
+		set: func(c *Config, value string) error {
+			if value != "" {
+				if _, err := zerolog.ParseLevel(value); err != nil {
+					return fmt.Errorf("invalid log level %q, must be one of trace, debug, info, warn, error", value)
+				}
+			}
+			c.LogLevel = value
+			return nil
+		},
+	},
+	{
+		key: "log-format",
+		get: func(c *Config) string { return c.LogFormat },
+		set: func(c *Config, value string) error {
+			if value != "" && value != "console" && value != "json" {
+				return fmt.Errorf("invalid log format %q, must be console or json", value)
+			}
+			c.LogFormat = value
+			return nil
+		},
+	},
+}
+
+func stringSetting(key string, field func(c *Config) *string) setting {
+	return setting{
+		key: key,
+		get: func(c *Config) string { return *field(c) },
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+	}
+}
+
+func durationSetting(key string, field func(c *Config) *time.Duration) setting {
+	return setting{
+		key: key,
+		get: func(c *Config) string {
+			if *field(c) == 0 {
+				return ""
+			}
+			return field(c).String()
+		},
+		set: func(c *Config, value string) error {
+			if value == "" {
+				*field(c) = 0
+				return nil
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid duration %q for %s, use a positive duration like 90s or 24h", value, key)
+			}
+			*field(c) = d
+			return nil
+		},
+	}
+}
+
+// Keys returns the keys of all settings.
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+// EnvName returns the environment variable overriding a setting, like PROVIDERS_TRASH_RETENTION.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// Get returns the value of a setting as a string, which is empty if it isn't set.
+func (c *Config) Get(key string) (string, error) {
+	s, err := find(key)
+	if err != nil {
+		return "", err
+	}
+	return s.get(c), nil
+}
+
+// Set parses and changes a setting. An empty value unsets it. Lists are comma separated.
+func (c *Config) Set(key, value string) error {
+	s, err := find(key)
+	if err != nil {
+		return err
+	}
+	return s.set(c, value)
+}
+
+// ApplyEnv overrides the settings for which an environment variable is set.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, s := range settings {
+		value, ok := lookup(EnvName(s.key))
+		if !ok {
+			continue
+		}
+		if err := s.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvName(s.key), err)
+		}
+	}
+	return nil
+}
+
+func find(key string) (setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	return setting{}, fmt.Errorf("unknown setting %q, must be one of %s", key, strings.Join(Keys(), ", "))
+}
+
+// DefaultPath returns ~/.config/providers/config.yaml.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home folder: %w", err)
+	}
+	return filepath.Join(home, ".config", "providers", "config.yaml"), nil
+}
+
+// Load reads the config file. A file which doesn't exist is an empty config.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the config file, creating its folder if needed.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config folder: %w", err)
+	}
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers", "config.yaml")
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{}, c)
+
+	require.NoError(t, c.Set("store", "memory"))
+	require.NoError(t, c.Set("catalogs", "a.yaml, https://example.com/catalog.yaml"))
+	require.NoError(t, c.Set("run-timeout", "90s"))
+	require.NoError(t, c.Save(path))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `store: memory
+catalogs:
+  - a.yaml
+  - https://example.com/catalog.yaml
+run-timeout: 1m30s
+`, string(b))
+
+	c, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Store:      "memory",
+		Catalogs:   []string{"a.yaml", "https://example.com/catalog.yaml"},
+		RunTimeout: 90 * time.Second,
+	}, c)
+
+	// an empty value unsets
+	require.NoError(t, c.Set("catalogs", ""))
+	assert.Nil(t, c.Catalogs)
+}
+
+func TestLoadRejectsUnknownSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("stroe: memory\n"), 0o600))
+	_, err := Load(path)
+	assert.Error(t, err)
+}
+
+func TestSet(t *testing.T) {
+	c := &Config{}
+	assert.EqualError(t, c.Set("nope", "x"), `unknown setting "nope", must be one of location, store, catalogs, trash-retention, run-timeout, http-timeout, log-level, log-format`)
+	assert.Error(t, c.Set("http-timeout", "soon"))
+	assert.Error(t, c.Set("http-timeout", "-1s"))
+	assert.Error(t, c.Set("log-level", "loud"))
+	require.NoError(t, c.Set("log-level", "debug"))
+	assert.Error(t, c.Set("log-format", "xml"))
+	require.NoError(t, c.Set("log-format", "json"))
+	require.NoError(t, c.Set("trash-retention", "24h"))
+	v, err := c.Get("trash-retention")
+	require.NoError(t, err)
+	assert.Equal(t, "24h0m0s", v)
+	v, err = c.Get("http-timeout")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestApplyEnv(t *testing.T) {
+	c := &Config{Store: "memory", Location: "/from/file"}
+	env := map[string]string{
+		"PROVIDERS_STORE":           "file:///registry",
+		"PROVIDERS_TRASH_RETENTION": "1h",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	require.NoError(t, c.ApplyEnv(lookup))
+	assert.Equal(t, "file:///registry", c.Store)
+	assert.Equal(t, "/from/file", c.Location)
+	assert.Equal(t, time.Hour, c.TrashRetention)
+
+	env["PROVIDERS_RUN_TIMEOUT"] = "later"
+	assert.EqualError(t, c.ApplyEnv(lookup), `invalid PROVIDERS_RUN_TIMEOUT: invalid duration "later" for run-timeout, use a positive duration like 90s or 24h`)
+	assert.Equal(t, "PROVIDERS_HTTP_TIMEOUT", EnvName("http-timeout"))
+}
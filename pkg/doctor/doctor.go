@@ -0,0 +1,194 @@
+// Package doctor checks the environment plugins are run in: the location, the database, the Docker daemon
+// and the binaries of bare plugins. Every check returns a Result, so a report can show everything which is
+// wrong at once instead of stopping at the first problem.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// Pass means nothing is wrong.
+	Pass Status = "pass"
+	// Warn means something should be looked at, but plugins can still be run.
+	Warn Status = "warn"
+	// Fail means something is broken.
+	Fail Status = "fail"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Check   string `json:"check" yaml:"check"`
+	Status  Status `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Report collects the results of all checks.
+type Report struct {
+	Results []*Result `json:"results" yaml:"results"`
+}
+
+// Add appends results to the report.
+func (r *Report) Add(results ...*Result) {
+	r.Results = append(r.Results, results...)
+}
+
+// Status returns the worst status of all results.
+func (r *Report) Status() Status {
+	status := Pass
+	for _, res := range r.Results {
+		switch {
+		case res.Status == Fail:
+			return Fail
+		case res.Status == Warn:
+			status = Warn
+		}
+	}
+	return status
+}
+
+// Database is a SQLite database which can be checked.
+type Database interface {
+	// SchemaVersion returns the version of the database and the latest version.
+	SchemaVersion(ctx context.Context) (int, int, error)
+	// IntegrityCheck returns the problems found in the database.
+	IntegrityCheck(ctx context.Context) ([]string, error)
+}
+
+// Docker is the Docker daemon container plugins are run with.
+type Docker interface {
+	ServerVersion(ctx context.Context) (string, error)
+}
+
+// Location checks that the location is a folder which can be written to and isn't writable by everyone.
+func Location(path string) *Result {
+	result := &Result{Check: "location", Status: Pass, Message: path}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		result.Status, result.Message = Fail, err.Error()
+		return result
+	case !info.IsDir():
+		result.Status, result.Message = Fail, path+" is not a folder"
+		return result
+	}
+	f, err := os.CreateTemp(path, ".doctor-*")
+	if err != nil {
+		result.Status, result.Message = Fail, fmt.Sprintf("%s is not writable: %s", path, err)
+		return result
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		result.Status, result.Message = Warn, fmt.Sprintf("failed to remove test file: %s", err)
+		return result
+	}
+	if info.Mode().Perm()&0o002 != 0 {
+		result.Status, result.Message = Warn, fmt.Sprintf("%s is writable by everyone (%s)", path, info.Mode().Perm())
+	}
+	return result
+}
+
+// DatabaseFile checks that the database file can be read and written. A missing database is created by
+// the next command which uses the store, so that is only a warning.
+func DatabaseFile(path string) *Result {
+	result := &Result{Check: "database", Status: Pass, Message: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		result.Status, result.Message = Warn, path+" doesn't exist yet, it is created by the next command"
+		return result
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		result.Status, result.Message = Fail, fmt.Sprintf("%s is not writable: %s", path, err)
+		return result
+	}
+	f.Close()
+	return result
+}
+
+// Schema checks that the database has the schema this version works with. An older schema is migrated by
+// the next command, a newer one means the executor is out of date.
+func Schema(ctx context.Context, db Database) *Result {
+	result := &Result{Check: "schema", Status: Pass}
+	version, latest, err := db.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		result.Status, result.Message = Fail, err.Error()
+	case version < latest:
+		result.Status, result.Message = Warn, fmt.Sprintf("version %d, migrated to %d by the next command", version, latest)
+	case version > latest:
+		result.Status, result.Message = Fail, fmt.Sprintf("version %d is newer than the supported version %d, upgrade the executor", version, latest)
+	default:
+		result.Message = fmt.Sprintf("version %d", version)
+	}
+	return result
+}
+
+// Integrity checks that the database isn't corrupt.
+func Integrity(ctx context.Context, db Database) *Result {
+	result := &Result{Check: "integrity", Status: Pass, Message: "ok"}
+	problems, err := db.IntegrityCheck(ctx)
+	switch {
+	case err != nil:
+		result.Status, result.Message = Fail, err.Error()
+	case len(problems) > 0:
+		result.Status, result.Message = Fail, strings.Join(problems, "; ")
+	}
+	return result
+}
+
+// DockerDaemon checks that the Docker daemon can be reached. That is only required if there are container
+// plugins, otherwise failing to reach it is a warning.
+func DockerDaemon(ctx context.Context, docker Docker, required bool) *Result {
+	result := &Result{Check: "docker", Status: Pass}
+	version, err := docker.ServerVersion(ctx)
+	switch {
+	case err != nil && required:
+		result.Status, result.Message = Fail, err.Error()
+	case err != nil:
+		result.Status, result.Message = Warn, "container plugins can't be run: "+err.Error()
+	default:
+		result.Message = version
+	}
+	return result
+}
+
+// BarePlugins checks that the binary of every bare plugin exists, is executable and matches its checksum.
+// Other plugins are skipped.
+func BarePlugins(ctx context.Context, inspector providers.Inspector, plugins []*models.Plugin) []*Result {
+	var results []*Result
+	for _, p := range plugins {
+		if p.Type != models.Bare {
+			continue
+		}
+		result := &Result{Check: "plugin " + providers.Ref(p.Name, p.Version), Status: Pass}
+		if p.Version == "" {
+			result.Check = "plugin " + p.Name
+		}
+		health, err := inspector.Inspect(ctx, p)
+		switch {
+		case err != nil:
+			result.Status, result.Message = Fail, err.Error()
+		case !health.Available:
+			result.Status, result.Message = Fail, health.Problem
+			// errors from stat already name the binary
+			if !strings.Contains(health.Problem, health.Source) {
+				result.Message = health.Source + ": " + health.Problem
+			}
+		case health.Checksum == providers.ChecksumMismatch:
+			result.Status, result.Message = Warn, health.Source+" doesn't match checksum "+p.Checksum
+		default:
+			result.Message = health.Source
+		}
+		results = append(results, result)
+	}
+	return results
+}
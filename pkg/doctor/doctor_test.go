@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers/bare"
+)
+
+type fakeDatabase struct {
+	version, latest int
+	problems        []string
+	err             error
+}
+
+func (f *fakeDatabase) SchemaVersion(ctx context.Context) (int, int, error) {
+	return f.version, f.latest, f.err
+}
+
+func (f *fakeDatabase) IntegrityCheck(ctx context.Context) ([]string, error) {
+	return f.problems, f.err
+}
+
+type fakeDocker struct {
+	err error
+}
+
+func (f *fakeDocker) ServerVersion(ctx context.Context) (string, error) {
+	return "20.10.12 (API 1.41)", f.err
+}
+
+func TestReportStatus(t *testing.T) {
+	r := &Report{}
+	assert.Equal(t, Pass, r.Status())
+	r.Add(&Result{Status: Pass}, &Result{Status: Warn})
+	assert.Equal(t, Warn, r.Status())
+	r.Add(&Result{Status: Fail}, &Result{Status: Warn})
+	assert.Equal(t, Fail, r.Status())
+}
+
+func TestLocation(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0o700))
+	assert.Equal(t, Pass, Location(dir).Status)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the test file is removed")
+
+	require.NoError(t, os.Chmod(dir, 0o777))
+	assert.Equal(t, Warn, Location(dir).Status)
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.Equal(t, Fail, Location(file).Status)
+	assert.Equal(t, Fail, Location(filepath.Join(dir, "missing")).Status)
+}
+
+func TestDatabaseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.db")
+	assert.Equal(t, Warn, DatabaseFile(path).Status)
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	assert.Equal(t, Pass, DatabaseFile(path).Status)
+}
+
+func TestDatabaseChecks(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		db        *fakeDatabase
+		schema    Status
+		integrity Status
+	}{
+		{name: "current", db: &fakeDatabase{version: 10, latest: 10}, schema: Pass, integrity: Pass},
+		{name: "outdated", db: &fakeDatabase{version: 8, latest: 10}, schema: Warn, integrity: Pass},
+		{name: "newer", db: &fakeDatabase{version: 11, latest: 10}, schema: Fail, integrity: Pass},
+		{name: "corrupt", db: &fakeDatabase{version: 10, latest: 10, problems: []string{"row 1 missing from index"}}, schema: Pass, integrity: Fail},
+		{name: "unreadable", db: &fakeDatabase{err: errors.New("file is not a database")}, schema: Fail, integrity: Fail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.schema, Schema(ctx, tt.db).Status)
+			assert.Equal(t, tt.integrity, Integrity(ctx, tt.db).Status)
+		})
+	}
+}
+
+func TestDockerDaemon(t *testing.T) {
+	ctx := context.Background()
+	result := DockerDaemon(ctx, &fakeDocker{}, true)
+	assert.Equal(t, Pass, result.Status)
+	assert.Equal(t, "20.10.12 (API 1.41)", result.Message)
+
+	unreachable := &fakeDocker{err: errors.New("cannot connect")}
+	assert.Equal(t, Fail, DockerDaemon(ctx, unreachable, true).Status)
+	assert.Equal(t, Warn, DockerDaemon(ctx, unreachable, false).Status)
+}
+
+func TestBarePlugins(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "echo"), []byte("#!/bin/sh\n"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), nil, 0o600))
+	plugin := func(name, version, checksum string) *models.Plugin {
+		return &models.Plugin{Name: name, Version: version, Type: models.Bare, Checksum: checksum, Bare: &models.BareMetalPlugin{Location: dir}}
+	}
+	inspector := bare.NewBareRunner(bare.Config{}, bare.Dependencies{Logger: zerolog.New(os.Stderr)})
+
+	results := BarePlugins(context.Background(), inspector, []*models.Plugin{
+		plugin("echo", "", ""),
+		plugin("echo", "1.0.0", "sha256:0000000000000000000000000000000000000000000000000000000000000000"),
+		plugin("data", "", ""),
+		plugin("missing", "", ""),
+		{Name: "hello", Type: models.Container, Container: &models.ContainerPlugin{Image: "hello"}},
+	})
+	var got []string
+	for _, r := range results {
+		got = append(got, string(r.Status)+" "+r.Check)
+	}
+	assert.Equal(t, []string{"pass plugin echo", "warn plugin echo@1.0.0", "fail plugin data", "fail plugin missing"}, got)
+}
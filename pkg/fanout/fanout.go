@@ -0,0 +1,175 @@
+// Package fanout runs several plugins at the same time, with a bound on how many run at once. Every line a
+// plugin writes is prefixed with its name, so the interleaved output can be told apart.
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Status is the outcome of running a plugin.
+type Status string
+
+const (
+	// Succeeded means the plugin exited successfully.
+	Succeeded Status = "succeeded"
+	// Failed means the plugin couldn't be run or exited with an error.
+	Failed Status = "failed"
+)
+
+// Result is the outcome of running a single plugin.
+type Result struct {
+	Plugin string `json:"plugin" yaml:"plugin"`
+	Status Status `json:"status" yaml:"status"`
+	// ExitCode is -1 if the plugin didn't exit by itself, because it couldn't be started or was killed.
+	ExitCode  int           `json:"exitCode" yaml:"exitCode"`
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`
+	StartedAt time.Time     `json:"startedAt" yaml:"startedAt"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+}
+
+// RunnerFunc returns the runner a plugin is run by, writing its output to stdout and stderr.
+type RunnerFunc func(stdout, stderr io.Writer) (providers.Runner, error)
+
+// Config defines parameters for the Executor.
+type Config struct {
+	// Parallel is the most plugins which run at once. Anything below 1 runs them one after the other.
+	Parallel int
+}
+
+// Dependencies defines the dependencies of the Executor.
+type Dependencies struct {
+	Logger    zerolog.Logger
+	NewRunner RunnerFunc
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// Executor runs plugins side by side.
+type Executor struct {
+	Config
+	Dependencies
+}
+
+// NewExecutor creates a new Executor.
+func NewExecutor(cfg Config, deps Dependencies) *Executor {
+	return &Executor{
+		Config:       cfg,
+		Dependencies: deps,
+	}
+}
+
+// Run runs every plugin with the same args. A failing plugin doesn't stop the others. The results are in
+// the order of plugins, and an error is returned if any of them failed.
+func (e *Executor) Run(ctx context.Context, plugins []string, args []string) ([]*Result, error) {
+	parallel := e.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	width := 0
+	for _, p := range plugins {
+		if len(p) > width {
+			width = len(p)
+		}
+	}
+	var (
+		wg sync.WaitGroup
+		// the lines of all plugins are written one at a time
+		m     sync.Mutex
+		slots = make(chan struct{}, parallel)
+	)
+	results := make([]*Result, len(plugins))
+	for i, plugin := range plugins {
+		results[i] = &Result{Plugin: plugin, ExitCode: -1}
+		wg.Add(1)
+		go func(result *Result) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			prefix := fmt.Sprintf("%-*s | ", width, result.Plugin)
+			stdout := &prefixWriter{m: &m, w: e.Stdout, prefix: prefix}
+			stderr := &prefixWriter{m: &m, w: e.Stderr, prefix: prefix}
+			e.run(ctx, result, args, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+		}(results[i])
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == Failed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d plugins failed", failed, len(plugins))
+	}
+	return results, nil
+}
+
+func (e *Executor) run(ctx context.Context, result *Result, args []string, stdout, stderr io.Writer) {
+	e.Logger.Debug().Str("plugin", result.Plugin).Msg("Starting plugin...")
+	result.StartedAt = time.Now()
+	runner, err := e.NewRunner(stdout, stderr)
+	if err == nil {
+		err = runner.Run(ctx, result.Plugin, args)
+	}
+	result.Duration = time.Since(result.StartedAt)
+	if err == nil {
+		result.Status, result.ExitCode = Succeeded, 0
+		return
+	}
+	result.Status, result.Error = Failed, err.Error()
+	// exec.ExitError and container.ExitError both tell the code
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	}
+}
+
+// prefixWriter writes every complete line to w with the prefix in front of it.
+type prefixWriter struct {
+	m      *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes what is left of a last line which didn't end with a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		_ = p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	_, err := p.w.Write(append([]byte(p.prefix), line...))
+	return err
+}
@@ -0,0 +1,116 @@
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// fakeRunner runs every plugin with the function of the same name.
+type fakeRunner struct {
+	stdout, stderr io.Writer
+	plugins        map[string]func(stdout, stderr io.Writer, args []string) error
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name string, args []string) error {
+	run, ok := f.plugins[name]
+	if !ok {
+		return fmt.Errorf("plugin not found: %w", providers.ErrNotFound)
+	}
+	return run(f.stdout, f.stderr, args)
+}
+
+func newExecutor(parallel int, stdout, stderr io.Writer, plugins map[string]func(stdout, stderr io.Writer, args []string) error) *Executor {
+	return NewExecutor(Config{Parallel: parallel}, Dependencies{
+		Logger: zerolog.New(os.Stderr),
+		NewRunner: func(stdout, stderr io.Writer) (providers.Runner, error) {
+			return &fakeRunner{stdout: stdout, stderr: stderr, plugins: plugins}, nil
+		},
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+func TestRun(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	e := newExecutor(2, stdout, stderr, map[string]func(stdout, stderr io.Writer, args []string) error{
+		"echo": func(stdout, stderr io.Writer, args []string) error {
+			// written in pieces, without a newline at the end
+			_, _ = io.WriteString(stdout, strings.Join(args, " ")+"\nsec")
+			_, _ = io.WriteString(stdout, "ond")
+			return nil
+		},
+		"fail": func(stdout, stderr io.Writer, args []string) error {
+			_, _ = io.WriteString(stderr, "boom\n")
+			return fmt.Errorf("failed to run plugin: %w", exitError(3))
+		},
+	})
+	results, err := e.Run(context.Background(), []string{"echo", "fail", "missing"}, []string{"a", "b"})
+	assert.EqualError(t, err, "2 of 3 plugins failed")
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "echo", results[0].Plugin)
+	assert.Equal(t, Succeeded, results[0].Status)
+	assert.Equal(t, 0, results[0].ExitCode)
+	assert.Equal(t, Failed, results[1].Status)
+	assert.Equal(t, 3, results[1].ExitCode)
+	assert.Equal(t, "failed to run plugin: exit status 3", results[1].Error)
+	assert.Equal(t, Failed, results[2].Status)
+	assert.Equal(t, -1, results[2].ExitCode)
+
+	assert.Equal(t, "echo    | a b\necho    | second\n", stdout.String())
+	assert.Equal(t, "fail    | boom\n", stderr.String())
+}
+
+func TestRunParallel(t *testing.T) {
+	var (
+		m                 sync.Mutex
+		running, highMark int
+	)
+	slow := func(stdout, stderr io.Writer, args []string) error {
+		m.Lock()
+		running++
+		if running > highMark {
+			highMark = running
+		}
+		m.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		m.Lock()
+		running--
+		m.Unlock()
+		return nil
+	}
+	plugins := map[string]func(stdout, stderr io.Writer, args []string) error{}
+	var names []string
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("slow-%d", i)
+		plugins[name] = slow
+		names = append(names, name)
+	}
+	results, err := newExecutor(2, io.Discard, io.Discard, plugins).Run(context.Background(), names, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, highMark)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Plugin)
+	}
+	assert.True(t, sort.StringsAreSorted(got), "results are in the order of the plugins")
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Audit actions.
+const (
+	// AuditCreate records a plugin being added.
+	AuditCreate = "create"
+	// AuditUpdate records a change to the definition of a plugin.
+	AuditUpdate = "update"
+	// AuditDelete records a plugin being removed.
+	AuditDelete = "delete"
+	// AuditRestore records a removed plugin being brought back from the trash.
+	AuditRestore = "restore"
+	// AuditActivate records a version becoming the active version of a plugin.
+	AuditActivate = "activate"
+)
+
+// AuditEvent records a single change made to a version of a plugin. Like Plugin, the json and yaml
+// field names are part of the output of the CLI.
+type AuditEvent struct {
+	ID int `json:"id" yaml:"id"`
+	// At is when the change was made.
+	At time.Time `json:"at" yaml:"at"`
+	// User is the operating system user who made the change.
+	User    string `json:"user" yaml:"user"`
+	Action  string `json:"action" yaml:"action"`
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Before is the plugin before the change, nil if it was created.
+	Before *Plugin `json:"before,omitempty" yaml:"before,omitempty"`
+	// After is the plugin after the change, nil if it was deleted.
+	After *Plugin `json:"after,omitempty" yaml:"after,omitempty"`
+}
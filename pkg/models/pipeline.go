@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Pipeline runs plugins chained together, the output of every step is the input of the next one.
+type Pipeline struct {
+	ID          int             `json:"id" yaml:"id"`
+	Name        string          `json:"name" yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Steps       []*PipelineStep `json:"steps" yaml:"steps"`
+	CreatedAt   time.Time       `json:"createdAt" yaml:"createdAt"`
+}
+
+// PipelineStep runs a single plugin of a pipeline.
+type PipelineStep struct {
+	// Plugin is the name of the plugin, or name@version to run a version which isn't active.
+	Plugin string `json:"plugin" yaml:"plugin"`
+	// Args are passed to the plugin as they are.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
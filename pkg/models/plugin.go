@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 const (
 	// Bare metal plugin type.
 	Bare = "bare"
@@ -7,21 +9,35 @@ const (
 	Container = "container"
 )
 
-// Plugin defines what a Plugin looks like.
+// Plugin defines what a Plugin looks like. The json and yaml field names are part of the
+// output of the CLI, scripts depend on them, so don't rename them.
 type Plugin struct {
-	ID        int
-	Name      string
-	Type      string
-	Container *ContainerPlugin
-	Bare      *BareMetalPlugin
+	ID      int    `json:"id" yaml:"id"`
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Active is true for the version of the plugin which is run if no version is asked for.
+	Active      bool   `json:"active" yaml:"active"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Owner is who to talk to about the plugin, a person or a team.
+	Owner  string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Checksum pins the plugin to the sha256 of its binary, or to the digest of its image,
+	// in the form sha256:<hex>. Empty if the plugin isn't pinned.
+	Checksum  string    `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+	// LastRun is the last time the plugin was run. Zero if it never ran.
+	LastRun   time.Time        `json:"lastRun" yaml:"lastRun"`
+	Container *ContainerPlugin `json:"container,omitempty" yaml:"container,omitempty"`
+	Bare      *BareMetalPlugin `json:"bare,omitempty" yaml:"bare,omitempty"`
 }
 
 // ContainerPlugin is a specific plugin which is in a container.
 type ContainerPlugin struct {
-	Image string
+	Image string `json:"image" yaml:"image"`
 }
 
 // BareMetalPlugin is a plugin which is a file on the filesystem.
 type BareMetalPlugin struct {
-	Location string
+	Location string `json:"location" yaml:"location"`
 }
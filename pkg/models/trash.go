@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// TrashedPlugin is a removed version of a plugin which can be restored until it expires.
+type TrashedPlugin struct {
+	Plugin    *Plugin   `json:"plugin" yaml:"plugin"`
+	DeletedAt time.Time `json:"deletedAt" yaml:"deletedAt"`
+	// ExpiresAt is when the plugin is deleted for good.
+	ExpiresAt time.Time `json:"expiresAt" yaml:"expiresAt"`
+}
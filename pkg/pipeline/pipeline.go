@@ -0,0 +1,178 @@
+// Package pipeline runs the steps of a pipeline chained together, like a shell pipeline: all steps run at
+// the same time and the stdout of every step streams into the stdin of the next one, whichever runner
+// runs them.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// namePattern is the same as the one of plugin names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Status is the outcome of a step.
+type Status string
+
+const (
+	// Succeeded means the plugin of the step exited successfully.
+	Succeeded Status = "succeeded"
+	// Failed means the step is the one which stopped the pipeline.
+	Failed Status = "failed"
+	// Cancelled means the step was stopped because another one failed, or because the next step exited
+	// without reading all of its output, like a shell pipeline stops a writer with SIGPIPE.
+	Cancelled Status = "cancelled"
+)
+
+// StepResult is the outcome of a single step.
+type StepResult struct {
+	// Step is the position of the step in the pipeline, starting at 1.
+	Step      int           `json:"step" yaml:"step"`
+	Plugin    string        `json:"plugin" yaml:"plugin"`
+	Args      []string      `json:"args,omitempty" yaml:"args,omitempty"`
+	Status    Status        `json:"status" yaml:"status"`
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`
+	StartedAt time.Time     `json:"startedAt" yaml:"startedAt"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+}
+
+// RunnerFunc returns the runner a step is run by. The plugin reads stdin, which is nil for the first step
+// if nothing is piped into the pipeline, and writes its output to stdout.
+type RunnerFunc func(stdin io.Reader, stdout io.Writer) (providers.Runner, error)
+
+// Dependencies defines the dependencies of the Executor.
+type Dependencies struct {
+	Logger    zerolog.Logger
+	NewRunner RunnerFunc
+}
+
+// Executor runs pipelines.
+type Executor struct {
+	Dependencies
+}
+
+// NewExecutor creates a new Executor.
+func NewExecutor(deps Dependencies) *Executor {
+	return &Executor{
+		Dependencies: deps,
+	}
+}
+
+// Validate checks that a pipeline has a valid name and at least one step, and that every step names a plugin.
+func Validate(p *models.Pipeline) error {
+	switch {
+	case p.Name == "":
+		return errors.New("invalid pipeline: name is required")
+	case !namePattern.MatchString(p.Name):
+		return fmt.Errorf("invalid pipeline: name %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", p.Name)
+	case len(p.Steps) == 0:
+		return errors.New("invalid pipeline: at least one step is required")
+	}
+	for i, s := range p.Steps {
+		if s == nil || s.Plugin == "" {
+			return fmt.Errorf("invalid pipeline: step %d: plugin is required", i+1)
+		}
+	}
+	return nil
+}
+
+// Run runs all steps of the pipeline. The first step reads stdin and the last one writes to stdout. Once a
+// step fails, the others are cancelled and the returned error names the step which failed. A step which
+// can't write anymore because the next one is done, like one piped into head, doesn't fail the pipeline.
+// The results of all steps are returned either way.
+func (e *Executor) Run(ctx context.Context, p *models.Pipeline, stdin io.Reader, stdout io.Writer) ([]*StepResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the runners are created up front, so nothing is started if one of them can't be
+	runners := make([]providers.Runner, len(p.Steps))
+	readers := make([]*io.PipeReader, len(p.Steps))
+	writers := make([]*io.PipeWriter, len(p.Steps))
+	in := stdin
+	for i := range p.Steps {
+		var out io.Writer = stdout
+		if i < len(p.Steps)-1 {
+			readers[i+1], writers[i] = io.Pipe()
+			out = writers[i]
+		}
+		runner, err := e.NewRunner(in, out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create runner for step %d: %w", i+1, err)
+		}
+		runners[i] = runner
+		if i < len(p.Steps)-1 {
+			in = readers[i+1]
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		m       sync.Mutex
+		failure error
+	)
+	results := make([]*StepResult, len(p.Steps))
+	for i, step := range p.Steps {
+		results[i] = &StepResult{Step: i + 1, Plugin: step.Plugin, Args: step.Args}
+		wg.Add(1)
+		go func(i int, step *models.PipelineStep) {
+			defer wg.Done()
+			result := results[i]
+			e.Logger.Debug().Int("step", result.Step).Str("plugin", step.Plugin).Msg("Starting step...")
+			result.StartedAt = time.Now()
+			err := runners[i].Run(ctx, step.Plugin, step.Args)
+			result.Duration = time.Since(result.StartedAt)
+
+			m.Lock()
+			switch {
+			case err == nil:
+				result.Status = Succeeded
+			case i < len(p.Steps)-1 && brokenPipe(err):
+				// the next step is done and didn't need the rest, that's no reason to stop the others
+				result.Status, result.Error = Cancelled, err.Error()
+			case failure == nil:
+				result.Status, result.Error = Failed, err.Error()
+				failure = fmt.Errorf("step %d (%s) failed: %w", result.Step, step.Plugin, err)
+				cancel()
+			default:
+				result.Status, result.Error = Cancelled, err.Error()
+			}
+			m.Unlock()
+
+			// the next step sees EOF, and the previous one can't block writing to a step which is done
+			if writers[i] != nil {
+				writers[i].Close()
+			}
+			if readers[i] != nil {
+				readers[i].Close()
+			}
+		}(i, step)
+	}
+	wg.Wait()
+	return results, failure
+}
+
+// brokenPipe tells whether a step failed writing to the next one because that one stopped reading. Runners
+// writing to the pipe themselves get io.ErrClosedPipe, the process of a bare plugin is killed by SIGPIPE.
+func brokenPipe(err error) bool {
+	if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+}
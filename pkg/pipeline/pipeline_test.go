@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// fakeRunner runs every plugin with the function of the same name.
+type fakeRunner struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	plugins map[string]func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name string, args []string) error {
+	return f.plugins[name](ctx, f.stdin, f.stdout, args)
+}
+
+var plugins = map[string]func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error{
+	"echo": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		_, err := io.WriteString(stdout, strings.Join(args, " "))
+		return err
+	},
+	"upper": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		in, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, strings.ToUpper(string(in)))
+		return err
+	},
+	"cat": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		_, err := io.Copy(stdout, stdin)
+		return err
+	},
+	"fail": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		return errors.New("exit status 1")
+	},
+	"yes": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		for {
+			if _, err := io.WriteString(stdout, "y\n"); err != nil {
+				return err
+			}
+		}
+	},
+	"head": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, line)
+		return err
+	},
+	"sleep": func(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	},
+}
+
+func newExecutor() *Executor {
+	return NewExecutor(Dependencies{
+		Logger: zerolog.New(os.Stderr),
+		NewRunner: func(stdin io.Reader, stdout io.Writer) (providers.Runner, error) {
+			return &fakeRunner{stdin: stdin, stdout: stdout, plugins: plugins}, nil
+		},
+	})
+}
+
+func newPipeline(steps ...*models.PipelineStep) *models.Pipeline {
+	return &models.Pipeline{Name: "test", Steps: steps}
+}
+
+func statuses(results []*StepResult) []Status {
+	var got []Status
+	for _, r := range results {
+		got = append(got, r.Status)
+	}
+	return got
+}
+
+func TestRun(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	results, err := newExecutor().Run(context.Background(), newPipeline(
+		&models.PipelineStep{Plugin: "cat"},
+		&models.PipelineStep{Plugin: "upper"},
+		&models.PipelineStep{Plugin: "cat"},
+	), strings.NewReader("hello"), stdout)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", stdout.String())
+	assert.Equal(t, []Status{Succeeded, Succeeded, Succeeded}, statuses(results))
+	assert.Equal(t, 2, results[1].Step)
+	assert.Equal(t, "upper", results[1].Plugin)
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	results, err := newExecutor().Run(context.Background(), newPipeline(
+		&models.PipelineStep{Plugin: "sleep"},
+		&models.PipelineStep{Plugin: "fail", Args: []string{"--now"}},
+		&models.PipelineStep{Plugin: "cat"},
+	), nil, stdout)
+	assert.EqualError(t, err, "step 2 (fail) failed: exit status 1")
+	// the last step gets EOF once the failed one is done, so it succeeds
+	assert.Equal(t, []Status{Cancelled, Failed, Succeeded}, statuses(results))
+	assert.Equal(t, "exit status 1", results[1].Error)
+	assert.Equal(t, []string{"--now"}, results[1].Args)
+	assert.Equal(t, context.Canceled.Error(), results[0].Error)
+}
+
+func TestRunStopsWritersLikeSIGPIPE(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	results, err := newExecutor().Run(context.Background(), newPipeline(
+		&models.PipelineStep{Plugin: "yes"},
+		&models.PipelineStep{Plugin: "cat"},
+		&models.PipelineStep{Plugin: "head"},
+	), nil, stdout)
+	require.NoError(t, err)
+	assert.Equal(t, "y\n", stdout.String())
+	assert.Equal(t, []Status{Cancelled, Cancelled, Succeeded}, statuses(results))
+	assert.Equal(t, io.ErrClosedPipe.Error(), results[0].Error)
+}
+
+func TestBrokenPipe(t *testing.T) {
+	assert.True(t, brokenPipe(fmt.Errorf("failed to run plugin: %w", io.ErrClosedPipe)))
+	assert.False(t, brokenPipe(errors.New("exit status 1")))
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run")
+	}
+	err := exec.Command("sh", "-c", "kill -PIPE $$").Run()
+	assert.True(t, brokenPipe(fmt.Errorf("failed to run plugin: %w", err)))
+	err = exec.Command("sh", "-c", "exit 1").Run()
+	assert.False(t, brokenPipe(err))
+}
+
+func TestRunNoRunner(t *testing.T) {
+	e := NewExecutor(Dependencies{
+		Logger: zerolog.New(os.Stderr),
+		NewRunner: func(stdin io.Reader, stdout io.Writer) (providers.Runner, error) {
+			return nil, errors.New("no docker")
+		},
+	})
+	results, err := e.Run(context.Background(), newPipeline(&models.PipelineStep{Plugin: "echo"}), nil, io.Discard)
+	assert.EqualError(t, err, "failed to create runner for step 1: no docker")
+	assert.Nil(t, results)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(newPipeline(&models.PipelineStep{Plugin: "echo@1.0.0"})))
+	assert.EqualError(t, Validate(&models.Pipeline{Steps: []*models.PipelineStep{{Plugin: "echo"}}}), "invalid pipeline: name is required")
+	assert.Error(t, Validate(&models.Pipeline{Name: "../etl", Steps: []*models.PipelineStep{{Plugin: "echo"}}}))
+	assert.EqualError(t, Validate(newPipeline()), "invalid pipeline: at least one step is required")
+	assert.EqualError(t, Validate(newPipeline(&models.PipelineStep{Plugin: "echo"}, &models.PipelineStep{})), "invalid pipeline: step 2: plugin is required")
+}
@@ -1,6 +1,8 @@
 package providers
 
+import "io"
+
 // Archiver can extract files from an archive.
 type Archiver interface {
-	Untar(content []byte) ([]byte, error)
+	Untar(dst string, r io.Reader) error
 }
@@ -0,0 +1,22 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// AuditOpts defines options for querying the audit log.
+type AuditOpts struct {
+	// Ref limits events to a plugin, or to a single version of it if given as `name@version`.
+	Ref string
+	// Since leaves out events which happened before it, unless it's zero.
+	Since time.Time
+}
+
+// Auditor is implemented by storers which record every change made to the plugins they store.
+type Auditor interface {
+	// AuditLog returns the recorded events matching the options, oldest first.
+	AuditLog(ctx context.Context, opts AuditOpts) ([]*models.AuditEvent, error)
+}
@@ -3,22 +3,36 @@ package bare
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/rs/zerolog"
 
+	"github.com/Skarlso/providers-example/pkg/models"
 	"github.com/Skarlso/providers-example/pkg/providers"
 )
 
 // Config contains the configuration for this runner.
 type Config struct {
+	// TTY connects the output of plugins straight to the terminal of the executor, instead of capturing it.
+	TTY bool
 }
 
 // Dependencies any providers which this provider needs.
 type Dependencies struct {
 	Logger zerolog.Logger
 	Storer providers.Storer
+	// Stdin is connected to the stdin of the plugin, if set. Otherwise the plugin reads from the null device.
+	Stdin io.Reader
+	// Stdout gets the output of the plugin as it is written, if set, instead of it being printed once the
+	// plugin is done. The plugin's stderr goes to the executor's.
+	Stdout io.Writer
+	// Stderr gets the plugin's stderr instead of the executor's, if set together with Stdout.
+	Stderr io.Writer
 }
 
 // Runner is a bare runner
@@ -27,7 +41,11 @@ type Runner struct {
 	Dependencies
 }
 
-var _ providers.Runner = &Runner{}
+var (
+	_ providers.Runner    = &Runner{}
+	_ providers.Inspector = &Runner{}
+	_ providers.Planner   = &Runner{}
+)
 
 // NewBareRunner creates a new Bare runner.
 func NewBareRunner(cfg Config, deps Dependencies) *Runner {
@@ -44,7 +62,21 @@ func (r *Runner) Run(ctx context.Context, name string, args []string) error {
 	if err != nil {
 		return fmt.Errorf("plugin not found: %w", err)
 	}
-	cmd := exec.Command(filepath.Join(plugin.Bare.Location, name), args...)
+	cmd := command(ctx, plugin, args)
+	cmd.Stdin = r.Stdin
+	if r.Stdout != nil || r.TTY {
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if r.Stdout != nil {
+			cmd.Stdout = r.Stdout
+			if r.Stderr != nil {
+				cmd.Stderr = r.Stderr
+			}
+		}
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to run plugin: %w", err)
+		}
+		return nil
+	}
 	output, err := cmd.Output()
 	if err != nil {
 		return fmt.Errorf("failed to run plugin: %w", err)
@@ -52,3 +84,83 @@ func (r *Runner) Run(ctx context.Context, name string, args []string) error {
 	fmt.Println(string(output))
 	return nil
 }
+
+// command returns the process a plugin is run as. The process is killed once ctx is done.
+func command(ctx context.Context, plugin *models.Plugin, args []string) *exec.Cmd {
+	return exec.CommandContext(ctx, filepath.Join(plugin.Bare.Location, plugin.Name), args...)
+}
+
+// Plan works out the process a bare metal plugin would be run as, without starting it.
+func (r *Runner) Plan(ctx context.Context, name string, args []string) (*providers.Plan, error) {
+	plugin, err := r.Storer.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin not found: %w", err)
+	}
+	if plugin.Bare == nil {
+		return nil, fmt.Errorf("plugin %s is not a bare metal plugin", plugin.Name)
+	}
+	cmd := command(ctx, plugin, args)
+	path, err := filepath.Abs(cmd.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	// without Dir and Env, the process inherits them from the executor
+	dir := cmd.Dir
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	names := make([]string, 0, len(env))
+	for _, e := range env {
+		names = append(names, strings.SplitN(e, "=", 2)[0])
+	}
+	sort.Strings(names)
+	return &providers.Plan{
+		Name:    plugin.Name,
+		Version: plugin.Version,
+		Type:    plugin.Type,
+		Bare: &providers.BarePlan{
+			Path:  path,
+			Args:  cmd.Args[1:],
+			Env:   names,
+			Dir:   dir,
+			Stdin: r.Stdin != nil,
+			TTY:   r.TTY,
+		},
+	}, nil
+}
+
+// Inspect checks that the binary of the plugin exists, is executable and matches its checksum.
+func (r *Runner) Inspect(ctx context.Context, plugin *models.Plugin) (*providers.Health, error) {
+	if plugin.Bare == nil {
+		return nil, fmt.Errorf("plugin %s is not a bare metal plugin", plugin.Name)
+	}
+	health := &providers.Health{
+		Source: filepath.Join(plugin.Bare.Location, plugin.Name),
+	}
+	info, err := os.Stat(health.Source)
+	switch {
+	case err != nil:
+		health.Problem = err.Error()
+	case !info.Mode().IsRegular():
+		health.Problem = "not a regular file"
+	case info.Mode().Perm()&0111 == 0:
+		health.Problem = "not executable"
+	default:
+		health.Available = true
+	}
+	if err == nil && info.Mode().IsRegular() {
+		digest, err := providers.FileDigest(health.Source)
+		if err != nil {
+			return nil, err
+		}
+		health.Digests = []string{digest}
+	}
+	health.VerifyChecksum(plugin.Checksum)
+	return health, nil
+}
@@ -0,0 +1,206 @@
+package bare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/memory"
+)
+
+func TestRun(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	location := t.TempDir()
+	err := os.WriteFile(filepath.Join(location, "echo"), []byte("#!/bin/sh\necho \"$@\"\n"), 0700)
+	assert.NoError(t, err)
+	err = store.Create(context.Background(), &models.Plugin{
+		Name: "echo",
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: location,
+		},
+	})
+	assert.NoError(t, err)
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: store,
+	})
+	err = r.Run(context.Background(), "echo", []string{"arg1", "arg2"})
+	assert.NoError(t, err)
+}
+
+func TestRunNotFound(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: memory.NewStorer(logger),
+	})
+	err := r.Run(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func TestInspect(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	location := t.TempDir()
+	err := os.WriteFile(filepath.Join(location, "echo"), []byte("#!/bin/sh\necho \"$@\"\n"), 0700)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(location, "data"), []byte("foo"), 0600)
+	assert.NoError(t, err)
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: memory.NewStorer(logger),
+	})
+	plugin := func(name, checksum string) *models.Plugin {
+		return &models.Plugin{
+			Name:     name,
+			Type:     models.Bare,
+			Checksum: checksum,
+			Bare: &models.BareMetalPlugin{
+				Location: location,
+			},
+		}
+	}
+
+	health, err := r.Inspect(context.Background(), plugin("echo", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(location, "echo"), health.Source)
+	assert.True(t, health.Available)
+	assert.Equal(t, providers.ChecksumNotPinned, health.Checksum)
+
+	// sha256 of "foo"
+	health, err = r.Inspect(context.Background(), plugin("data", "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"))
+	assert.NoError(t, err)
+	assert.False(t, health.Available)
+	assert.Equal(t, "not executable", health.Problem)
+	assert.Equal(t, providers.ChecksumMatch, health.Checksum)
+
+	health, err = r.Inspect(context.Background(), plugin("echo", "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"))
+	assert.NoError(t, err)
+	assert.Equal(t, providers.ChecksumMismatch, health.Checksum)
+
+	health, err = r.Inspect(context.Background(), plugin("missing", "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"))
+	assert.NoError(t, err)
+	assert.False(t, health.Available)
+	assert.Equal(t, providers.ChecksumUnknown, health.Checksum)
+}
+
+func TestPlan(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	location := t.TempDir()
+	err := store.Create(context.Background(), &models.Plugin{
+		Name:    "echo",
+		Version: "1.0.0",
+		Type:    models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: location,
+		},
+	})
+	assert.NoError(t, err)
+	t.Setenv("PLAN_SECRET", "hunter2")
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: store,
+	})
+
+	plan, err := r.Plan(context.Background(), "echo", []string{"arg1", "arg2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", plan.Version)
+	assert.Equal(t, filepath.Join(location, "echo"), plan.Bare.Path)
+	assert.Equal(t, []string{"arg1", "arg2"}, plan.Bare.Args)
+	assert.Contains(t, plan.Bare.Env, "PLAN_SECRET")
+	assert.NotContains(t, plan.Bare.Env, "PLAN_SECRET=hunter2")
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.Equal(t, wd, plan.Bare.Dir)
+	assert.Nil(t, plan.Container)
+
+	_, err = r.Plan(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func TestRunPassesArgsVerbatim(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	location := t.TempDir()
+	out := filepath.Join(t.TempDir(), "args")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"[$a]\" >> " + out + "; done\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(location, "args"), []byte(script), 0700))
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name: "args",
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: location,
+		},
+	}))
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: store,
+	})
+	err := r.Run(context.Background(), "args", []string{"echo this", "a,b", "", "$HOME"})
+	assert.NoError(t, err)
+	got, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "[echo this]\n[a,b]\n[]\n[$HOME]\n", string(got))
+}
+
+func TestRunForwardsStdin(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	location := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	// cat only finishes once it reads EOF
+	assert.NoError(t, os.WriteFile(filepath.Join(location, "filter"), []byte("#!/bin/sh\ncat > "+out+"\n"), 0700))
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name: "filter",
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: location,
+		},
+	}))
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: store,
+		Stdin:  strings.NewReader(`{"a":1}`),
+	})
+	assert.NoError(t, r.Run(context.Background(), "filter", nil))
+	got, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(got))
+
+	plan, err := r.Plan(context.Background(), "filter", nil)
+	assert.NoError(t, err)
+	assert.True(t, plan.Bare.Stdin)
+}
+
+func TestRunStreamsStdout(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	location := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(location, "upper"), []byte("#!/bin/sh\ntr a-z A-Z\nexit 2\n"), 0700))
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name: "upper",
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: location,
+		},
+	}))
+	stdout := &strings.Builder{}
+	r := NewBareRunner(Config{}, Dependencies{
+		Logger: logger,
+		Storer: store,
+		Stdin:  strings.NewReader("hello\n"),
+		Stdout: stdout,
+	})
+	err := r.Run(context.Background(), "upper", nil)
+	assert.EqualError(t, err, "failed to run plugin: exit status 2")
+	assert.Equal(t, "HELLO\n", stdout.String())
+}
@@ -0,0 +1,283 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/validation"
+)
+
+// Catalog lists the plugins which can be installed and their released versions. It is read from
+// YAML or JSON files, which can be local or served over HTTP:
+//
+//	plugins:
+//	  - name: echo
+//	    type: bare
+//	    versions:
+//	      - version: 1.2.0
+//	        archive: echo-1.2.0.tar.gz
+//	        checksum: sha256:<hex of the archive>
+//	  - name: hello
+//	    type: container
+//	    versions:
+//	      - version: 1.0.0
+//	        image: skarlso/providers:hello-v1.0.0
+type Catalog struct {
+	Plugins []*Entry `json:"plugins" yaml:"plugins"`
+}
+
+// Entry is a plugin in the catalog.
+type Entry struct {
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Type        string     `json:"type" yaml:"type"`
+	Versions    []*Release `json:"versions" yaml:"versions"`
+}
+
+// Release is a version of a plugin in the catalog.
+type Release struct {
+	Version string `json:"version" yaml:"version"`
+	// Image is the image of a container plugin.
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+	// Archive is the location of the tar.gz archive containing the binary of a bare plugin. A relative
+	// location is relative to the catalog file it's listed in.
+	Archive string `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// Checksum is the sha256 of the archive, or the digest of the image, in the form sha256:<hex>.
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+
+	// base is the location of the catalog file the release was read from.
+	base string
+}
+
+// Load reads and merges the catalogs at the given sources. A source is an http(s) URL or the path of a file
+// or of a directory of .yaml, .yml and .json files. If a plugin is listed more than once, the first one wins.
+func Load(ctx context.Context, client *http.Client, sources []string) (*Catalog, error) {
+	result := &Catalog{}
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		locations, err := expand(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, location := range locations {
+			c, err := read(ctx, client, location)
+			if err != nil {
+				return nil, err
+			}
+			if err := c.validate(); err != nil {
+				return nil, fmt.Errorf("invalid catalog %s: %w", location, err)
+			}
+			for _, e := range c.Plugins {
+				if seen[e.Name] {
+					continue
+				}
+				seen[e.Name] = true
+				for _, r := range e.Versions {
+					r.base = location
+				}
+				result.Plugins = append(result.Plugins, e)
+			}
+		}
+	}
+	return result, nil
+}
+
+// validate checks the names and versions of the plugins, which end up in the paths plugins are installed to.
+func (c *Catalog) validate() error {
+	for _, e := range c.Plugins {
+		if err := validation.Name(e.Name); err != nil {
+			return err
+		}
+		for _, r := range e.Versions {
+			if err := validation.Version(r.Version); err != nil {
+				return fmt.Errorf("plugin %s: %w", e.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Find returns the named plugin or nil.
+func (c *Catalog) Find(name string) *Entry {
+	for _, e := range c.Plugins {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Resolve returns the plugin and release a reference like `echo` or `echo@1.2.0` refers to. A plain name refers
+// to the newest release which isn't a pre-release.
+func (c *Catalog) Resolve(ref string) (*Entry, *Release, error) {
+	name, version, pinned := providers.ParseRef(ref)
+	entry := c.Find(name)
+	if entry == nil {
+		return nil, nil, fmt.Errorf("plugin %s is not in the catalog", name)
+	}
+	if pinned {
+		release := entry.Release(version)
+		if release == nil {
+			return nil, nil, fmt.Errorf("version %s of %s is not in the catalog", version, name)
+		}
+		return entry, release, nil
+	}
+	release, err := entry.Latest("")
+	if err != nil {
+		return nil, nil, err
+	}
+	if release == nil {
+		return nil, nil, fmt.Errorf("plugin %s has no release in the catalog", name)
+	}
+	return entry, release, nil
+}
+
+// Search returns the plugins whose name or description contains the term, ignoring case, ordered by name.
+// An empty term returns every plugin.
+func (c *Catalog) Search(term string) []*Entry {
+	term = strings.ToLower(term)
+	var result []*Entry
+	for _, e := range c.Plugins {
+		if strings.Contains(strings.ToLower(e.Name), term) || strings.Contains(strings.ToLower(e.Description), term) {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Latest returns the highest version matching the constraint, like `^1.2` or `>= 1.0, < 2`. An empty constraint
+// matches every version which isn't a pre-release. Versions which aren't semantic versions, like `1.2`, never
+// match, just like they're never newer than one when installed. It returns nil if no version matches.
+func (e *Entry) Latest(constraint string) (*Release, error) {
+	if constraint == "" {
+		constraint = "*"
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+	var latest *Release
+	for _, r := range e.Versions {
+		v, err := providers.ParseVersion(r.Version)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if latest == nil || providers.VersionKey(r.Version) > providers.VersionKey(latest.Version) {
+			latest = r
+		}
+	}
+	return latest, nil
+}
+
+// Release returns the given version or nil.
+func (e *Entry) Release(version string) *Release {
+	for _, r := range e.Versions {
+		if r.Version == version {
+			return r
+		}
+	}
+	return nil
+}
+
+// ArchiveLocation returns the location of the archive, resolving relative locations.
+func (r *Release) ArchiveLocation() (string, error) {
+	if r.Archive == "" || isURL(r.Archive) || r.base == "" {
+		return r.Archive, nil
+	}
+	if isURL(r.base) {
+		base, err := url.Parse(r.base)
+		if err != nil {
+			return "", fmt.Errorf("invalid catalog url %q: %w", r.base, err)
+		}
+		ref, err := url.Parse(r.Archive)
+		if err != nil {
+			return "", fmt.Errorf("invalid archive location %q: %w", r.Archive, err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+	if filepath.IsAbs(r.Archive) {
+		return r.Archive, nil
+	}
+	return filepath.Join(filepath.Dir(r.base), r.Archive), nil
+}
+
+// Open opens a local file or downloads a URL.
+func Open(ctx context.Context, client *http.Client, location string) (io.ReadCloser, error) {
+	if !isURL(location) {
+		return os.Open(location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// expand returns the catalog files of a source, sorted by name if it is a directory.
+func expand(source string) ([]string, error) {
+	if isURL(source) {
+		return []string{source}, nil
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{source}, nil
+	}
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(source, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func read(ctx context.Context, client *http.Client, location string) (*Catalog, error) {
+	r, err := Open(ctx, client, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	defer r.Close()
+	c := &Catalog{}
+	// JSON is valid YAML, so this reads both
+	if err := yaml.NewDecoder(r).Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", location, err)
+	}
+	return c, nil
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
@@ -0,0 +1,330 @@
+package catalog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/memory"
+	tarer "github.com/Skarlso/providers-example/pkg/providers/tar"
+)
+
+// archive returns a tar.gz archive containing a single executable and its checksum.
+func archive(t *testing.T, name, content string) ([]byte, string) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// catalogServer serves a catalog of the echo plugin with versions 1.1.0, 1.2.0 and 2.0.0-rc.1.
+func catalogServer(t *testing.T) *httptest.Server {
+	files := map[string][]byte{}
+	checksums := map[string]string{}
+	for _, v := range []string{"1.1.0", "1.2.0", "2.0.0-rc.1"} {
+		files["/echo-"+v+".tar.gz"], checksums[v] = archive(t, "echo", "#!/bin/sh\necho "+v+"\n")
+	}
+	files["/catalog.yaml"] = []byte(`plugins:
+  - name: echo
+    description: Echoes its arguments.
+    type: bare
+    versions:
+      - version: 1.1.0
+        archive: echo-1.1.0.tar.gz
+        checksum: ` + checksums["1.1.0"] + `
+      - version: 1.2.0
+        archive: /echo-1.2.0.tar.gz
+        checksum: ` + checksums["1.2.0"] + `
+      - version: 2.0.0-rc.1
+        archive: echo-2.0.0-rc.1.tar.gz
+        checksum: ` + checksums["2.0.0-rc.1"] + `
+  - name: hello
+    type: container
+    versions:
+      - version: 1.0.0
+        image: skarlso/providers:hello-v1.0.0
+`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newInstaller(t *testing.T, server *httptest.Server) *Installer {
+	logger := zerolog.New(os.Stderr)
+	return NewInstaller(Config{
+		Dir: t.TempDir(),
+	}, Dependencies{
+		Logger:   logger,
+		Storer:   memory.NewStorer(logger),
+		Archiver: tarer.NewTarer(logger),
+		Client:   server.Client(),
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("plugins:\n  - name: echo\n    type: bare\n    versions:\n      - version: 0.1.0\n        archive: echo.tar.gz\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"plugins": [{"name": "echo", "type": "bare"}, {"name": "other", "type": "container", "versions": [{"version": "1.0.0", "image": "other:1.0.0"}]}]}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a catalog"), 0644))
+
+	c, err := Load(context.Background(), http.DefaultClient, []string{dir})
+	require.NoError(t, err)
+	require.Len(t, c.Plugins, 2)
+	echo := c.Find("echo")
+	require.NotNil(t, echo)
+	// the first catalog listing a plugin wins
+	require.Len(t, echo.Versions, 1)
+	location, err := echo.Versions[0].ArchiveLocation()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "echo.tar.gz"), location)
+	assert.NotNil(t, c.Find("other"))
+	assert.Nil(t, c.Find("missing"))
+
+	_, err = Load(context.Background(), http.DefaultClient, []string{filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestSearch(t *testing.T) {
+	server := catalogServer(t)
+	c, err := Load(context.Background(), server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+
+	names := func(entries []*Entry) []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Name)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"echo", "hello"}, names(c.Search("")))
+	assert.Equal(t, []string{"hello"}, names(c.Search("HEL")))
+	// descriptions are searched too
+	assert.Equal(t, []string{"echo"}, names(c.Search("arguments")))
+	assert.Empty(t, c.Search("missing"))
+
+	_, err = Load(context.Background(), server.Client(), []string{server.URL + "/missing.yaml"})
+	assert.Error(t, err)
+}
+
+func TestLatest(t *testing.T) {
+	server := catalogServer(t)
+	c, err := Load(context.Background(), server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+	echo := c.Find("echo")
+	require.NotNil(t, echo)
+
+	for constraint, want := range map[string]string{
+		"":              "1.2.0",
+		"~1.1":          "1.1.0",
+		">= 2.0.0-rc.0": "2.0.0-rc.1",
+	} {
+		r, err := echo.Latest(constraint)
+		require.NoError(t, err)
+		require.NotNil(t, r, constraint)
+		assert.Equal(t, want, r.Version, constraint)
+	}
+	r, err := echo.Latest("^3")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+	_, err = echo.Latest("not a constraint")
+	assert.Error(t, err)
+
+	// only semantic versions are compared, the way installed versions are sorted
+	loose := &Entry{Name: "loose", Versions: []*Release{{Version: "1.3"}, {Version: "latest"}}}
+	r, err = loose.Latest("^1")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+	loose.Versions = append(loose.Versions, &Release{Version: "v1.2.1"})
+	r, err = loose.Latest("^1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.1", r.Version)
+}
+
+func TestUpgrade(t *testing.T) {
+	ctx := context.Background()
+	server := catalogServer(t)
+	c, err := Load(ctx, server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+	i := newInstaller(t, server)
+
+	installed, err := i.Install(ctx, c.Find("echo"), c.Find("echo").Release("1.1.0"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(i.Dir, "echo", "1.1.0"), installed.Bare.Location)
+	content, err := os.ReadFile(filepath.Join(installed.Bare.Location, "echo"))
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\necho 1.1.0\n", string(content))
+	digest, err := providers.FileDigest(filepath.Join(installed.Bare.Location, "echo"))
+	require.NoError(t, err)
+	assert.Equal(t, digest, installed.Checksum)
+	_, err = i.Install(ctx, c.Find("echo"), c.Find("echo").Release("1.1.0"))
+	assert.ErrorIs(t, err, providers.ErrAlreadyExists)
+
+	outdated, err := FindOutdated(ctx, i.Storer, c)
+	require.NoError(t, err)
+	assert.Equal(t, []*Outdated{{Name: "echo", Current: "1.1.0", Latest: "1.2.0"}}, outdated)
+
+	p, changed, err := i.Upgrade(ctx, c, "echo", "")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "1.2.0", p.Version)
+	active, err := i.Storer.Get(ctx, "echo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", active.Version)
+	assert.Equal(t, "Echoes its arguments.", active.Description)
+
+	outdated, err = FindOutdated(ctx, i.Storer, c)
+	require.NoError(t, err)
+	assert.Empty(t, outdated)
+	_, changed, err = i.Upgrade(ctx, c, "echo", "")
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// an explicit constraint can go back to a version which is already installed
+	p, changed, err = i.Upgrade(ctx, c, "echo", "~1.1")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "1.1.0", p.Version)
+	plugins, err := i.Storer.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Len(t, plugins, 2)
+
+	_, _, err = i.Upgrade(ctx, c, "hello", "")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func TestResolve(t *testing.T) {
+	server := catalogServer(t)
+	c, err := Load(context.Background(), server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+
+	entry, release, err := c.Resolve("echo")
+	require.NoError(t, err)
+	assert.Equal(t, "echo", entry.Name)
+	assert.Equal(t, "1.2.0", release.Version)
+	_, release, err = c.Resolve("echo@2.0.0-rc.1")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0-rc.1", release.Version)
+
+	_, _, err = c.Resolve("echo@3.0.0")
+	assert.Error(t, err)
+	_, _, err = c.Resolve("missing")
+	assert.Error(t, err)
+}
+
+func TestInstallChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	server := catalogServer(t)
+	c, err := Load(ctx, server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+	i := newInstaller(t, server)
+	echo := c.Find("echo")
+	release := echo.Release("1.2.0")
+	release.Checksum = "sha256:0000"
+
+	_, err = i.Install(ctx, echo, release)
+	assert.Error(t, err)
+	_, err = i.Storer.Get(ctx, "echo@1.2.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	entries, err := os.ReadDir(i.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestInstallContainer(t *testing.T) {
+	ctx := context.Background()
+	server := catalogServer(t)
+	c, err := Load(ctx, server.Client(), []string{server.URL + "/catalog.yaml"})
+	require.NoError(t, err)
+	i := newInstaller(t, server)
+
+	entry, release, err := c.Resolve("hello")
+	require.NoError(t, err)
+	p, err := i.Install(ctx, entry, release)
+	require.NoError(t, err)
+	assert.Equal(t, models.Container, p.Type)
+	assert.Equal(t, "skarlso/providers:hello-v1.0.0", p.Container.Image)
+	p, err = i.Storer.Get(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", p.Version)
+}
+
+func TestHostileCatalog(t *testing.T) {
+	ctx := context.Background()
+	for name, catalog := range map[string]string{
+		"name":    "plugins:\n  - name: ../x\n    type: bare\n",
+		"version": "plugins:\n  - name: echo\n    type: bare\n    versions:\n      - version: ../../..\n        archive: echo.tar.gz\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "catalog.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(catalog), 0644))
+			_, err := Load(ctx, http.DefaultClient, []string{path})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid catalog")
+		})
+	}
+
+	// entries which didn't come through Load can't escape the plugins folder either
+	content, checksum := archive(t, "echo", "#!/bin/sh\n")
+	base := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(base, "echo.tar.gz"), content, 0644))
+	victim := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(victim, "keep"), nil, 0644))
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	i := newInstaller(t, server)
+	for _, tt := range []struct{ name, version string }{
+		{name: "echo", version: "../../.."},
+		{name: "echo", version: "."},
+		{name: "..", version: filepath.Base(victim)},
+		{name: "../" + filepath.Base(victim), version: ".."},
+	} {
+		entry := &Entry{Name: tt.name, Type: models.Bare}
+		release := &Release{Version: tt.version, Archive: filepath.Join(base, "echo.tar.gz"), Checksum: checksum}
+		_, err := i.Install(ctx, entry, release)
+		require.Error(t, err, "name %q version %q", tt.name, tt.version)
+		assert.Contains(t, err.Error(), "outside of")
+	}
+	assert.FileExists(t, filepath.Join(victim, "keep"))
+}
+
+func TestInstallRejectsInvalidDefinition(t *testing.T) {
+	ctx := context.Background()
+	server := catalogServer(t)
+	i := newInstaller(t, server)
+	entry := &Entry{Name: "hello", Type: models.Container}
+	release := &Release{Version: "1.0.0", Image: "Not An Image", Checksum: "sha256:short"}
+
+	_, err := i.Install(ctx, entry, release)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container.image")
+	assert.Contains(t, err.Error(), "checksum")
+	_, err = i.Storer.Get(ctx, "hello@1.0.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/validation"
+)
+
+// Config defines parameters for the Installer.
+type Config struct {
+	// Dir is where the archives of bare plugins are extracted to, every version into <Dir>/<name>/<version>.
+	Dir string
+}
+
+// Dependencies defines the providers the Installer needs.
+type Dependencies struct {
+	Logger   zerolog.Logger
+	Storer   providers.Storer
+	Archiver providers.Archiver
+	Client   *http.Client
+}
+
+// Installer adds releases from the catalog to the store.
+type Installer struct {
+	Config
+	Dependencies
+}
+
+// NewInstaller creates a new Installer.
+func NewInstaller(cfg Config, deps Dependencies) *Installer {
+	return &Installer{
+		Config:       cfg,
+		Dependencies: deps,
+	}
+}
+
+// Install adds a release of a plugin to the store. The archive of a bare plugin is downloaded, verified against
+// the checksum of the release and extracted first. Installing a version which is already stored fails with
+// providers.ErrAlreadyExists.
+func (i *Installer) Install(ctx context.Context, entry *Entry, release *Release) (*models.Plugin, error) {
+	ref := providers.Ref(entry.Name, release.Version)
+	if _, err := i.Storer.Get(ctx, ref); err == nil {
+		return nil, fmt.Errorf("failed to install %s: %w", ref, providers.ErrAlreadyExists)
+	} else if !errors.Is(err, providers.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check installed versions: %w", err)
+	}
+	i.Logger.Info().Str("name", entry.Name).Str("version", release.Version).Msg("Installing plugin...")
+	plugin := &models.Plugin{
+		Name:        entry.Name,
+		Version:     release.Version,
+		Type:        entry.Type,
+		Description: entry.Description,
+	}
+	switch entry.Type {
+	case models.Container:
+		if release.Image == "" {
+			return nil, fmt.Errorf("release %s has no image", ref)
+		}
+		plugin.Container = &models.ContainerPlugin{
+			Image: release.Image,
+		}
+		plugin.Checksum = release.Checksum
+	case models.Bare:
+		dir, err := i.extract(ctx, entry, release)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := providers.FileDigest(filepath.Join(dir, entry.Name))
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("archive of %s doesn't contain the binary %s: %w", ref, entry.Name, err)
+		}
+		plugin.Bare = &models.BareMetalPlugin{
+			Location: dir,
+		}
+		plugin.Checksum = digest
+	default:
+		return nil, fmt.Errorf("unknown plugin type %q of %s", entry.Type, entry.Name)
+	}
+	// the same checks as for plugins which are added by hand, the binary was checked above already
+	if err := validation.Definition(plugin); err != nil {
+		if plugin.Bare != nil {
+			_ = os.RemoveAll(plugin.Bare.Location)
+		}
+		return nil, fmt.Errorf("failed to install %s: %w", ref, err)
+	}
+	if err := i.Storer.Create(ctx, plugin); err != nil {
+		if plugin.Bare != nil {
+			_ = os.RemoveAll(plugin.Bare.Location)
+		}
+		return nil, fmt.Errorf("failed to store plugin: %w", err)
+	}
+	return plugin, nil
+}
+
+// extract downloads and verifies the archive of a release and extracts it into its own directory.
+func (i *Installer) extract(ctx context.Context, entry *Entry, release *Release) (string, error) {
+	location, err := release.ArchiveLocation()
+	if err != nil {
+		return "", err
+	}
+	if location == "" {
+		return "", fmt.Errorf("release %s has no archive", providers.Ref(entry.Name, release.Version))
+	}
+	r, err := Open(ctx, i.Client, location)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read archive: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	if digest := "sha256:" + hex.EncodeToString(sum[:]); release.Checksum != "" && digest != release.Checksum {
+		return "", fmt.Errorf("checksum of %s is %s instead of %s", location, digest, release.Checksum)
+	} else if release.Checksum == "" {
+		i.Logger.Warn().Str("archive", location).Msg("The release has no checksum, the archive can't be verified.")
+	}
+
+	// extract next to the final directory and rename it, so a failed install leaves nothing half written behind
+	parent := filepath.Join(i.Dir, entry.Name)
+	dir := filepath.Join(parent, release.Version)
+	// the name and version come from the catalog, which is checked when it's loaded, but whatever is
+	// removed below has to be inside Dir
+	if !within(i.Dir, parent) || !within(parent, dir) {
+		return "", fmt.Errorf("release %s would be installed outside of %s", providers.Ref(entry.Name, release.Version), i.Dir)
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".install-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := i.Archiver.Untar(tmp, bytes.NewReader(content)); err != nil {
+		_ = os.RemoveAll(tmp)
+		return "", fmt.Errorf("failed to extract archive: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		_ = os.RemoveAll(tmp)
+		return "", fmt.Errorf("failed to remove previous installation: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		_ = os.RemoveAll(tmp)
+		return "", fmt.Errorf("failed to move plugin into place: %w", err)
+	}
+	return dir, nil
+}
+
+// within returns true if path is a folder below dir, and not dir itself.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Outdated is an installed plugin for which the catalog has a newer version.
+type Outdated struct {
+	Name    string `json:"name" yaml:"name"`
+	Current string `json:"current" yaml:"current"`
+	Latest  string `json:"latest" yaml:"latest"`
+}
+
+// FindOutdated compares the active version of every installed plugin with the latest release in the catalog.
+// Plugins which aren't in the catalog are left out.
+func FindOutdated(ctx context.Context, store providers.Storer, c *Catalog) ([]*Outdated, error) {
+	plugins, err := store.List(ctx, providers.ListOpts{ActiveOnly: true, SortBy: providers.SortByName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	var result []*Outdated
+	for _, p := range plugins {
+		entry := c.Find(p.Name)
+		if entry == nil {
+			continue
+		}
+		latest, err := entry.Latest("")
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil && providers.VersionKey(latest.Version) > providers.VersionKey(p.Version) {
+			result = append(result, &Outdated{Name: p.Name, Current: p.Version, Latest: latest.Version})
+		}
+	}
+	return result, nil
+}
+
+// Upgrade switches an installed plugin to the newest version in the catalog matching the constraint, installing
+// it first if needed. Without a constraint, a plugin is never downgraded. It returns the active version and whether
+// it changed.
+func (i *Installer) Upgrade(ctx context.Context, c *Catalog, name, constraint string) (*models.Plugin, bool, error) {
+	current, err := i.Storer.Get(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get installed plugin: %w", err)
+	}
+	entry := c.Find(name)
+	if entry == nil {
+		return nil, false, fmt.Errorf("plugin %s is not in the catalog", name)
+	}
+	release, err := entry.Latest(constraint)
+	if err != nil {
+		return nil, false, err
+	}
+	if release == nil {
+		return nil, false, fmt.Errorf("no version of %s matches %q", name, constraint)
+	}
+	key, currentKey := providers.VersionKey(release.Version), providers.VersionKey(current.Version)
+	if key == currentKey || constraint == "" && key < currentKey {
+		return current, false, nil
+	}
+
+	plugin, err := i.Storer.Get(ctx, providers.Ref(name, release.Version))
+	if errors.Is(err, providers.ErrNotFound) {
+		plugin, err = i.Install(ctx, entry, release)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := i.Storer.Activate(ctx, name, release.Version); err != nil {
+		return nil, false, fmt.Errorf("failed to activate %s: %w", providers.Ref(name, release.Version), err)
+	}
+	plugin.Active = true
+	return plugin, true, nil
+}
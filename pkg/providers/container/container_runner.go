@@ -3,16 +3,17 @@ package container
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/term"
 	"github.com/rs/zerolog"
 
 	"github.com/Skarlso/providers-example/pkg/models"
@@ -21,7 +22,12 @@ import (
 
 // Config defines parameters for the Runner.
 type Config struct {
-	DefaultMaximumCommandRuntime int
+	// DefaultMaximumCommandRuntime is how long a container may run. With zero, containers run for as long
+	// as they take.
+	DefaultMaximumCommandRuntime time.Duration
+	// TTY allocates a terminal for the container and attaches it to the terminal of the executor, for
+	// plugins which prompt or are a REPL.
+	TTY bool
 }
 
 // Dependencies defines the provider dependencies this provider has.
@@ -29,6 +35,13 @@ type Dependencies struct {
 	Next   providers.Runner
 	Storer providers.Storer
 	Logger zerolog.Logger
+	// Stdin is forwarded to the container, if set. The container sees EOF once Stdin is drained.
+	Stdin io.Reader
+	// Stdout gets the output of the container as it is written, if set, instead of it being printed once the
+	// container exits. The container's stderr goes to the executor's.
+	Stdout io.Writer
+	// Stderr gets the container's stderr instead of the executor's, if set together with Stdout.
+	Stderr io.Writer
 }
 
 // Runner implements the Run interface for container based runtimes.
@@ -39,6 +52,12 @@ type Runner struct {
 	cli client.APIClient
 }
 
+var (
+	_ providers.Runner    = &Runner{}
+	_ providers.Inspector = &Runner{}
+	_ providers.Planner   = &Runner{}
+)
+
 // NewRunner creates a new container based runtime.
 func NewRunner(cfg Config, deps Dependencies) (*Runner, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
@@ -67,87 +86,130 @@ func (cr *Runner) Run(ctx context.Context, name string, args []string) error {
 		}
 		return cr.Next.Run(ctx, name, args)
 	}
-	if err := cr.runCommand(cmd.Name, cmd.Container.Image, args); err != nil {
+	if err := cr.runCommand(ctx, cmd.Name, cmd.Container.Image, args); err != nil {
 		return fmt.Errorf("failed to run command: %w", err)
 	}
 	return nil
 }
 
 // runCommand takes a command name and an image and the necessary arguments and runs the container and waits for output.
-func (cr *Runner) runCommand(commandName, image string, args []string) error {
+func (cr *Runner) runCommand(ctx context.Context, commandName, image string, args []string) error {
 	output, err := cr.cli.ImagePull(context.Background(), image, types.ImagePullOptions{})
 	if err != nil {
 		cr.Logger.Debug().Err(err).Msg("Failed to pull image.")
 		return err
 	}
-	if _, err := io.Copy(os.Stdout, output); err != nil {
+	// the pull progress mustn't end up in the output of the container
+	progress := io.Writer(os.Stdout)
+	if cr.Stdout != nil {
+		progress = os.Stderr
+	}
+	if _, err := io.Copy(progress, output); err != nil {
 		cr.Logger.Debug().Err(err).Msg("Failed to pull image.")
 		return err
 	}
 
 	cr.Logger.Info().Msg("Creating container...")
-	cont, err := cr.cli.ContainerCreate(context.Background(), &container.Config{
-		AttachStdout: true,
-		AttachStderr: true,
-		Image:        image,
-		Cmd:          args,
-	}, nil, nil, nil, "")
+	config, hostConfig := containerConfig(image, args, cr.Stdin != nil, cr.TTY)
+	cont, err := cr.cli.ContainerCreate(context.Background(), config, hostConfig, nil, nil, "")
 	if err != nil {
 		cr.Logger.Debug().Err(err).Strs("warnings", cont.Warnings).Msg("Failed to create container.")
 		return err
 	}
-	cr.startAndWaitForContainer(commandName, cont.ID)
-	return nil
+	if cr.TTY {
+		return cr.runInteractive(ctx, commandName, cont.ID)
+	}
+	return cr.startAndWaitForContainer(ctx, commandName, cont.ID)
+}
+
+// containerConfig returns what the container of a plugin is created with. The host config is nil, so the
+// daemon's defaults are used.
+func containerConfig(image string, args []string, stdin, tty bool) (*container.Config, *container.HostConfig) {
+	config := &container.Config{
+		AttachStdout: true,
+		AttachStderr: true,
+		Image:        image,
+		Tty:          tty,
+		// with StdinOnce, closing the attached stdin closes it in the container too
+		AttachStdin: stdin,
+		OpenStdin:   stdin,
+		StdinOnce:   stdin,
+	}
+	// an empty command would override the image's default one with nothing
+	if len(args) > 0 {
+		config.Cmd = args
+	}
+	return config, nil
 }
 
 // runCommand takes a single command and executes it, waiting for it to finish,
 // or tcr out. Either way, it will update the corresponding command row.
-func (cr *Runner) startAndWaitForContainer(commandName, containerID string) {
+// The container is killed once ctx is done.
+func (cr *Runner) startAndWaitForContainer(ctx context.Context, commandName, containerID string) error {
 	cr.Logger.Info().Str("name", commandName).Msg("Starting running command...")
-	done := make(chan error, 1)
-	defer func() {
-		// we remove the container in a `defer` instead of autoRemove, to be able to read out the logs.
-		// If we use AutoRemove, the container is gone by the tcr we want to read the output.
-		// Could try streaming the logs. But this is enough for now.
-		if err := cr.cli.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{
-			Force: true,
-		}); err != nil {
-			cr.Logger.Debug().Err(err).Str("container_id", containerID).Msg("Failed to remove container.")
+	// we remove the container in a `defer` instead of autoRemove, to be able to read out the logs.
+	// If we use AutoRemove, the container is gone by the tcr we want to read the output.
+	// Could try streaming the logs. But this is enough for now.
+	defer cr.removeContainer(containerID)
+
+	var output chan struct{}
+	if cr.Stdin != nil || cr.Stdout != nil {
+		// attach before starting, so nothing written to stdin is lost and no output is missed
+		attach, err := cr.cli.ContainerAttach(context.Background(), containerID, types.ContainerAttachOptions{
+			Stream: true,
+			Stdin:  cr.Stdin != nil,
+			Stdout: cr.Stdout != nil,
+			Stderr: cr.Stdout != nil,
+		})
+		if err != nil {
+			cr.Logger.Error().Err(err).Msg("Failed to attach to container.")
+			return fmt.Errorf("failed to attach to container: %w", err)
 		}
-	}()
+		defer attach.Close()
+		if cr.Stdin != nil {
+			go cr.forwardStdin(attach)
+		}
+		if cr.Stdout != nil {
+			output = make(chan struct{})
+			stderr := cr.Stderr
+			if stderr == nil {
+				stderr = os.Stderr
+			}
+			go func() {
+				if _, err := stdcopy.StdCopy(cr.Stdout, stderr, attach.Reader); err != nil {
+					cr.Logger.Debug().Err(err).Msg("Failed to copy the output of the container.")
+				}
+				close(output)
+			}()
+		}
+	}
 
 	cr.Logger.Info().Msg("Starting container...")
 	if err := cr.cli.ContainerStart(context.Background(), containerID, types.ContainerStartOptions{}); err != nil {
-		return
+		cr.Logger.Error().Err(err).Msg("Failed to start container.")
+		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	go func() {
-		exit, err := cr.cli.ContainerWait(context.Background(), containerID, container.WaitConditionNotRunning)
-		select {
-		case e := <-err:
-			done <- e
-		case e := <-exit:
-			if e.StatusCode != 0 {
-				if e.Error != nil {
-					done <- errors.New(e.Error.Message)
-				} else {
-					done <- fmt.Errorf("status code: %d", e.StatusCode)
-				}
-			} else {
-				done <- nil
-			}
-		}
-	}()
-
+	done := cr.waitForExit(containerID)
+	timeout := cr.timeout()
 	for {
 		select {
 		case err := <-done:
+			if output != nil {
+				// the output was streamed already, let the rest of it through
+				<-output
+				if err != nil {
+					cr.Logger.Error().Err(err).Msg("Failed to run command.")
+				}
+				return err
+			}
 			log, logErr := cr.cli.ContainerLogs(context.Background(), containerID, types.ContainerLogsOptions{
 				ShowStderr: true,
 				ShowStdout: true,
 			})
 			if logErr != nil {
-				return
+				cr.Logger.Debug().Err(logErr).Msg("Failed to read the container logs.")
+				return err
 			}
 			buffer := &bytes.Buffer{}
 			logs := "no logs available"
@@ -158,20 +220,271 @@ func (cr *Runner) startAndWaitForContainer(commandName, containerID string) {
 			}
 
 			if err != nil {
-				cr.Logger.Debug().Err(err).Msg("Failed to run command.")
+				// the logs can be long, they are only shown at debug level
+				cr.Logger.Error().Err(err).Msg("Failed to run command.")
 				cr.Logger.Debug().Str("logs", logs).Msg("Logs from the attached container.")
-				return
+				return err
 			}
 			cr.Logger.Info().Msg("Successfully finished command. Output: ")
 			fmt.Println(buffer.String())
-			return
-		case <-time.After(time.Duration(cr.DefaultMaximumCommandRuntime) * time.Second):
+			return nil
+		case <-timeout:
 			// update entry
 			cr.Logger.Error().Msg("Command tcrd out.")
-			if err := cr.cli.ContainerKill(context.Background(), containerID, "SIGKILL"); err != nil {
-				cr.Logger.Error().Str("container_id", containerID).Msg("Failed to kill process with pid.")
+			cr.killContainer(containerID)
+			return fmt.Errorf("command timed out after %s", cr.DefaultMaximumCommandRuntime)
+		case <-ctx.Done():
+			cr.killContainer(containerID)
+			return ctx.Err()
+		}
+	}
+}
+
+// runInteractive starts a container created with a TTY and connects it to the terminal of the executor
+// until it exits. The terminal is put into raw mode, so every key press goes to the container, and the
+// container's terminal is resized along with it.
+func (cr *Runner) runInteractive(ctx context.Context, commandName, containerID string) error {
+	defer cr.removeContainer(containerID)
+	attach, err := cr.cli.ContainerAttach(ctx, containerID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  cr.Stdin != nil,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach to container: %w", err)
+	}
+	defer attach.Close()
+
+	cr.Logger.Info().Str("name", commandName).Msg("Starting container...")
+	if err := cr.cli.ContainerStart(ctx, containerID, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	// the output of a TTY isn't multiplexed, it's copied as it is
+	output := make(chan struct{})
+	go func() {
+		if _, err := io.Copy(os.Stdout, attach.Reader); err != nil {
+			cr.Logger.Debug().Err(err).Msg("Failed to copy the output of the container.")
+		}
+		close(output)
+	}()
+	if fd, ok := term.GetFdInfo(cr.Stdin); ok && term.IsTerminal(fd) {
+		state, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer func() {
+			if err := term.RestoreTerminal(fd, state); err != nil {
+				cr.Logger.Error().Err(err).Msg("Failed to restore terminal.")
+			}
+		}()
+	}
+	if cr.Stdin != nil {
+		go cr.forwardStdin(attach)
+	}
+
+	resized, stop := notifyResize()
+	defer stop()
+	cr.resize(ctx, containerID)
+	done := cr.waitForExit(containerID)
+	timeout := cr.timeout()
+	for {
+		select {
+		case <-resized:
+			cr.resize(ctx, containerID)
+		case err := <-done:
+			// let the rest of the output through, the stream ends with the container
+			<-output
+			return err
+		case <-timeout:
+			cr.killContainer(containerID)
+			return fmt.Errorf("command timed out after %s", cr.DefaultMaximumCommandRuntime)
+		case <-ctx.Done():
+			cr.killContainer(containerID)
+			return ctx.Err()
+		}
+	}
+}
+
+// resize sets the size of the container's terminal to the size of the executor's.
+func (cr *Runner) resize(ctx context.Context, containerID string) {
+	fd, ok := term.GetFdInfo(os.Stdout)
+	if !ok || !term.IsTerminal(fd) {
+		return
+	}
+	size, err := term.GetWinsize(fd)
+	if err != nil {
+		cr.Logger.Debug().Err(err).Msg("Failed to get terminal size.")
+		return
+	}
+	if err := cr.cli.ContainerResize(ctx, containerID, types.ResizeOptions{
+		Height: uint(size.Height),
+		Width:  uint(size.Width),
+	}); err != nil {
+		cr.Logger.Debug().Err(err).Msg("Failed to resize the terminal of the container.")
+	}
+}
+
+// waitForExit returns a channel which gets the error the container exited with, nil if it succeeded.
+func (cr *Runner) waitForExit(containerID string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		exit, err := cr.cli.ContainerWait(context.Background(), containerID, container.WaitConditionNotRunning)
+		select {
+		case e := <-err:
+			done <- e
+		case e := <-exit:
+			if e.StatusCode != 0 {
+				exitErr := &ExitError{Code: int(e.StatusCode)}
+				if e.Error != nil {
+					exitErr.Message = e.Error.Message
+				}
+				done <- exitErr
+			} else {
+				done <- nil
+			}
+		}
+	}()
+	return done
+}
+
+// ExitError is returned when a container exits with a non-zero status code.
+type ExitError struct {
+	Code int
+	// Message is the error reported by the daemon, if any.
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("status code: %d", e.Code)
+}
+
+// ExitCode returns the status code, like exec.ExitError does for processes.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// timeout returns a channel which fires once the maximum runtime is over, or never if there is none.
+func (cr *Runner) timeout() <-chan time.Time {
+	if cr.DefaultMaximumCommandRuntime <= 0 {
+		return nil
+	}
+	return time.After(cr.DefaultMaximumCommandRuntime)
+}
+
+func (cr *Runner) killContainer(containerID string) {
+	if err := cr.cli.ContainerKill(context.Background(), containerID, "SIGKILL"); err != nil {
+		cr.Logger.Error().Str("container_id", containerID).Msg("Failed to kill process with pid.")
+	}
+}
+
+func (cr *Runner) removeContainer(containerID string) {
+	if err := cr.cli.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{
+		Force: true,
+	}); err != nil {
+		cr.Logger.Debug().Err(err).Str("container_id", containerID).Msg("Failed to remove container.")
+	}
+}
+
+// Plan works out the container a plugin would be run in, without pulling the image or creating the
+// container. Other plugins are handed to the next runner, if it can plan them.
+func (cr *Runner) Plan(ctx context.Context, name string, args []string) (*providers.Plan, error) {
+	cmd, err := cr.Storer.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin not found: %w", err)
+	}
+	if cmd.Type != models.Container {
+		next, ok := cr.Next.(providers.Planner)
+		if !ok {
+			return nil, fmt.Errorf("no next provider configured which can plan %s", cmd.Name)
+		}
+		return next.Plan(ctx, name, args)
+	}
+	config, hostConfig := containerConfig(cmd.Container.Image, args, cr.Stdin != nil, cr.TTY)
+	plan := &providers.ContainerPlan{
+		Image:      cmd.Container.Image,
+		Pull:       "always",
+		Timeout:    cr.DefaultMaximumCommandRuntime,
+		Config:     config,
+		HostConfig: hostConfig,
+	}
+	image, _, err := cr.cli.ImageInspectWithRaw(ctx, cmd.Container.Image)
+	switch {
+	case client.IsErrNotFound(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	default:
+		plan.Present = true
+		plan.Digest = image.ID
+		if len(image.RepoDigests) > 0 {
+			if i := strings.LastIndex(image.RepoDigests[0], "@"); i >= 0 {
+				plan.Digest = image.RepoDigests[0][i+1:]
+			}
+		}
+	}
+	return &providers.Plan{
+		Name:      cmd.Name,
+		Version:   cmd.Version,
+		Type:      cmd.Type,
+		Container: plan,
+	}, nil
+}
+
+// forwardStdin copies Stdin to the attached container and closes the stream for writing once Stdin is
+// drained, which the container reads as EOF.
+func (cr *Runner) forwardStdin(attach types.HijackedResponse) {
+	if _, err := io.Copy(attach.Conn, cr.Stdin); err != nil {
+		cr.Logger.Debug().Err(err).Msg("Failed to forward stdin to the container.")
+	}
+	if err := attach.CloseWrite(); err != nil {
+		cr.Logger.Debug().Err(err).Msg("Failed to close stdin of the container.")
+	}
+}
+
+// ServerVersion connects to the Docker daemon container plugins are run with and returns its version.
+func (cr *Runner) ServerVersion(ctx context.Context) (string, error) {
+	v, err := cr.cli.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to docker: %w", err)
+	}
+	return fmt.Sprintf("%s (API %s)", v.Version, v.APIVersion), nil
+}
+
+// Inspect checks that the image of a container plugin is present locally and matches its checksum.
+// Other plugins are handed to the next runner, if it can inspect them.
+func (cr *Runner) Inspect(ctx context.Context, plugin *models.Plugin) (*providers.Health, error) {
+	if plugin.Type != models.Container {
+		next, ok := cr.Next.(providers.Inspector)
+		if !ok {
+			return nil, fmt.Errorf("no next provider configured which can inspect %s", plugin.Name)
+		}
+		return next.Inspect(ctx, plugin)
+	}
+	if plugin.Container == nil {
+		return nil, fmt.Errorf("plugin %s has no image", plugin.Name)
+	}
+	health := &providers.Health{
+		Source: plugin.Container.Image,
+	}
+	image, _, err := cr.cli.ImageInspectWithRaw(ctx, plugin.Container.Image)
+	switch {
+	case client.IsErrNotFound(err):
+		health.Problem = "image not present locally"
+	case err != nil:
+		health.Problem = fmt.Sprintf("failed to inspect image: %s", err)
+	default:
+		health.Available = true
+		health.Digests = append(health.Digests, image.ID)
+		for _, d := range image.RepoDigests {
+			// repository digests look like skarlso/providers@sha256:<hex>
+			if i := strings.LastIndex(d, "@"); i >= 0 {
+				health.Digests = append(health.Digests, d[i+1:])
 			}
-			return
 		}
 	}
+	health.VerifyChecksum(plugin.Checksum)
+	return health, nil
 }
@@ -1,22 +1,30 @@
 package container
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
 	networktypes "github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/Skarlso/providers-example/pkg/models"
-	"github.com/Skarlso/providers-example/pkg/providers/fakes"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/memory"
 )
 
 type mockDockerClient struct {
@@ -26,6 +34,31 @@ type mockDockerClient struct {
 	createOutput    containertypes.ContainerCreateCreatedBody
 	logsOutput      io.ReadCloser
 	containerOkChan chan containertypes.ContainerWaitOKBody
+	images          map[string]types.ImageInspect
+	version         *types.Version
+	attached        *attachConn
+	attachOptions   types.ContainerAttachOptions
+	output          string
+}
+
+// attachConn records what is written to the stdin of an attached container.
+type attachConn struct {
+	net.Conn
+	stdin  bytes.Buffer
+	closed chan struct{}
+}
+
+func (c *attachConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+func (c *attachConn) CloseWrite() error {
+	close(c.closed)
+	return nil
+}
+
+func (c *attachConn) Close() error {
+	return nil
 }
 
 func (mc *mockDockerClient) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
@@ -56,9 +89,33 @@ func (mc *mockDockerClient) ContainerLogs(ctx context.Context, container string,
 	return mc.logsOutput, nil
 }
 
+func (mc *mockDockerClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	inspect, ok := mc.images[image]
+	if !ok {
+		return types.ImageInspect{}, nil, errdefs.NotFound(errors.New("no such image"))
+	}
+	return inspect, nil, nil
+}
+
+func (mc *mockDockerClient) ContainerAttach(ctx context.Context, container string, options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	mc.attachOptions = options
+	return types.HijackedResponse{Conn: mc.attached, Reader: bufio.NewReader(strings.NewReader(mc.output))}, nil
+}
+
+func (mc *mockDockerClient) ContainerResize(ctx context.Context, container string, options types.ResizeOptions) error {
+	return nil
+}
+
+func (mc *mockDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	if mc.version == nil {
+		return types.Version{}, errors.New("cannot connect to the docker daemon")
+	}
+	return *mc.version, nil
+}
+
 func TestCreateRun(t *testing.T) {
 	logger := zerolog.New(os.Stderr)
-	fakeStorer := &fakes.FakeStorer{}
+	store := memory.NewStorer(logger)
 	imagePullOutput := &bytes.Buffer{}
 	imagePullOutput.WriteString("success")
 	logsOutput := &bytes.Buffer{}
@@ -75,27 +132,304 @@ func TestCreateRun(t *testing.T) {
 	}
 	r := Runner{
 		Dependencies: Dependencies{
-			Storer: fakeStorer,
+			Storer: store,
 			Logger: logger,
 		},
 		Config: Config{
-			DefaultMaximumCommandRuntime: 15,
+			DefaultMaximumCommandRuntime: 15 * time.Second,
 		},
 		cli: apiClient,
 	}
-	fakeStorer.GetReturns(&models.Plugin{
-		ID:   1,
+	err := store.Create(context.Background(), &models.Plugin{
 		Name: "test",
 		Type: models.Container,
 		Container: &models.ContainerPlugin{
 			Image: "test-image",
 		},
-	}, nil)
+	})
+	assert.NoError(t, err)
 	go func() {
 		apiClient.containerOkChan <- containertypes.ContainerWaitOKBody{
 			StatusCode: 0,
 		}
 	}()
-	err := r.Run(context.Background(), "test", []string{"arg1", "arg2"})
+	err = r.Run(context.Background(), "test", []string{"arg1", "arg2"})
+	assert.NoError(t, err)
+}
+
+func TestInspect(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: memory.NewStorer(logger),
+			Logger: logger,
+		},
+		cli: &mockDockerClient{
+			images: map[string]types.ImageInspect{
+				"skarlso/providers:echo-v1": {
+					ID:          "sha256:1111",
+					RepoDigests: []string{"skarlso/providers@sha256:2222"},
+				},
+			},
+		},
+	}
+	plugin := func(image, checksum string) *models.Plugin {
+		return &models.Plugin{
+			Name:     "echo",
+			Type:     models.Container,
+			Checksum: checksum,
+			Container: &models.ContainerPlugin{
+				Image: image,
+			},
+		}
+	}
+
+	health, err := r.Inspect(context.Background(), plugin("skarlso/providers:echo-v1", "sha256:2222"))
+	assert.NoError(t, err)
+	assert.True(t, health.Available)
+	assert.Equal(t, []string{"sha256:1111", "sha256:2222"}, health.Digests)
+	assert.Equal(t, providers.ChecksumMatch, health.Checksum)
+
+	health, err = r.Inspect(context.Background(), plugin("skarlso/providers:echo-v2", ""))
+	assert.NoError(t, err)
+	assert.False(t, health.Available)
+	assert.Equal(t, "image not present locally", health.Problem)
+	assert.Equal(t, providers.ChecksumNotPinned, health.Checksum)
+
+	// bare plugins need a next runner which can inspect them
+	_, err = r.Inspect(context.Background(), &models.Plugin{Name: "bare", Type: models.Bare})
+	assert.Error(t, err)
+}
+
+func TestServerVersion(t *testing.T) {
+	r := Runner{
+		Dependencies: Dependencies{
+			Logger: zerolog.New(os.Stderr),
+		},
+		cli: &mockDockerClient{
+			version: &types.Version{Version: "20.10.12", APIVersion: "1.41"},
+		},
+	}
+	version, err := r.ServerVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "20.10.12 (API 1.41)", version)
+
+	r.cli = &mockDockerClient{}
+	_, err = r.ServerVersion(context.Background())
+	assert.EqualError(t, err, "failed to connect to docker: cannot connect to the docker daemon")
+}
+
+func TestPlan(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+		},
+		Config: Config{
+			DefaultMaximumCommandRuntime: 15 * time.Second,
+		},
+		cli: &mockDockerClient{
+			images: map[string]types.ImageInspect{
+				"skarlso/providers:echo-v1": {
+					ID:          "sha256:1111",
+					RepoDigests: []string{"skarlso/providers@sha256:2222"},
+				},
+			},
+		},
+	}
+	for _, p := range []*models.Plugin{
+		{Name: "echo", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:echo-v1"}},
+		{Name: "hello", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:hello-v1"}},
+		{Name: "bare", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/bin"}},
+	} {
+		assert.NoError(t, store.Create(context.Background(), p))
+	}
+
+	plan, err := r.Plan(context.Background(), "echo", []string{"arg1", "arg2"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Container, plan.Type)
+	assert.True(t, plan.Container.Present)
+	assert.Equal(t, "sha256:2222", plan.Container.Digest)
+	assert.Equal(t, 15*time.Second, plan.Container.Timeout)
+	assert.Equal(t, "skarlso/providers:echo-v1", plan.Container.Config.Image)
+	assert.Equal(t, []string{"arg1", "arg2"}, []string(plan.Container.Config.Cmd))
+	assert.Nil(t, plan.Bare)
+
+	plan, err = r.Plan(context.Background(), "hello", []string{})
 	assert.NoError(t, err)
+	assert.False(t, plan.Container.Present)
+	assert.Empty(t, plan.Container.Digest)
+	assert.Nil(t, plan.Container.Config.Cmd, "the image's default command runs")
+
+	// bare plugins need a next runner which can plan them
+	_, err = r.Plan(context.Background(), "bare", nil)
+	assert.Error(t, err)
+}
+
+func TestRunForwardsStdin(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	apiClient := &mockDockerClient{
+		imagePullOutput: io.NopCloser(&bytes.Buffer{}),
+		logsOutput:      io.NopCloser(&bytes.Buffer{}),
+		containerOkChan: make(chan containertypes.ContainerWaitOKBody),
+		attached:        &attachConn{closed: make(chan struct{})},
+	}
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+			Stdin:  strings.NewReader(`{"a":1}`),
+		},
+		Config: Config{
+			DefaultMaximumCommandRuntime: 15 * time.Second,
+		},
+		cli: apiClient,
+	}
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name:      "filter",
+		Type:      models.Container,
+		Container: &models.ContainerPlugin{Image: "filter"},
+	}))
+	go func() {
+		// the container only exits once its stdin is closed
+		<-apiClient.attached.closed
+		apiClient.containerOkChan <- containertypes.ContainerWaitOKBody{}
+	}()
+	assert.NoError(t, r.Run(context.Background(), "filter", nil))
+	assert.Equal(t, `{"a":1}`, apiClient.attached.stdin.String())
+
+	plan, err := r.Plan(context.Background(), "filter", nil)
+	assert.NoError(t, err)
+	assert.True(t, plan.Container.Config.OpenStdin)
+	assert.True(t, plan.Container.Config.StdinOnce)
+}
+
+func TestRunInteractive(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	apiClient := &mockDockerClient{
+		imagePullOutput: io.NopCloser(&bytes.Buffer{}),
+		containerOkChan: make(chan containertypes.ContainerWaitOKBody, 1),
+		attached:        &attachConn{closed: make(chan struct{})},
+		output:          "> ",
+	}
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+		},
+		Config: Config{
+			TTY: true,
+		},
+		cli: apiClient,
+	}
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name:      "repl",
+		Type:      models.Container,
+		Container: &models.ContainerPlugin{Image: "repl"},
+	}))
+
+	// without a timeout, the container runs until it exits
+	apiClient.containerOkChan <- containertypes.ContainerWaitOKBody{StatusCode: 3}
+	err := r.Run(context.Background(), "repl", nil)
+	assert.EqualError(t, err, "failed to run command: status code: 3")
+	assert.True(t, apiClient.attachOptions.Stdout)
+	assert.False(t, apiClient.attachOptions.Stdin)
+
+	plan, err := r.Plan(context.Background(), "repl", nil)
+	assert.NoError(t, err)
+	assert.True(t, plan.Container.Config.Tty)
+}
+
+func TestRunStreamsStdout(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	// without a TTY, the attached stream multiplexes stdout and stderr
+	stream := &bytes.Buffer{}
+	_, err := stdcopy.NewStdWriter(stream, stdcopy.Stdout).Write([]byte("line 1\n"))
+	assert.NoError(t, err)
+	_, err = stdcopy.NewStdWriter(stream, stdcopy.Stderr).Write([]byte("warning\n"))
+	assert.NoError(t, err)
+	apiClient := &mockDockerClient{
+		imagePullOutput: io.NopCloser(&bytes.Buffer{}),
+		containerOkChan: make(chan containertypes.ContainerWaitOKBody, 1),
+		output:          stream.String(),
+	}
+	stdout := &bytes.Buffer{}
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+			Stdout: stdout,
+		},
+		cli: apiClient,
+	}
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name:      "extract",
+		Type:      models.Container,
+		Container: &models.ContainerPlugin{Image: "extract"},
+	}))
+
+	apiClient.containerOkChan <- containertypes.ContainerWaitOKBody{StatusCode: 1}
+	err = r.Run(context.Background(), "extract", nil)
+	assert.EqualError(t, err, "failed to run command: status code: 1")
+	var exitErr *ExitError
+	assert.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode())
+	assert.Equal(t, "line 1\n", stdout.String())
+	assert.True(t, apiClient.attachOptions.Stdout)
+	assert.False(t, apiClient.attachOptions.Stdin)
+}
+
+func TestRunCancelled(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	apiClient := &mockDockerClient{
+		imagePullOutput: io.NopCloser(&bytes.Buffer{}),
+		containerOkChan: make(chan containertypes.ContainerWaitOKBody),
+	}
+	r := Runner{
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+		},
+		cli: apiClient,
+	}
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name:      "sleep",
+		Type:      models.Container,
+		Container: &models.ContainerPlugin{Image: "sleep"},
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, r.Run(ctx, "sleep", nil), context.Canceled)
+}
+
+func TestRunTimesOutWithinASecond(t *testing.T) {
+	logger := zerolog.New(os.Stderr)
+	store := memory.NewStorer(logger)
+	apiClient := &mockDockerClient{
+		imagePullOutput: io.NopCloser(&bytes.Buffer{}),
+		containerOkChan: make(chan containertypes.ContainerWaitOKBody),
+	}
+	r := Runner{
+		Config: Config{
+			DefaultMaximumCommandRuntime: 50 * time.Millisecond,
+		},
+		Dependencies: Dependencies{
+			Storer: store,
+			Logger: logger,
+		},
+		cli: apiClient,
+	}
+	assert.NoError(t, store.Create(context.Background(), &models.Plugin{
+		Name:      "sleep",
+		Type:      models.Container,
+		Container: &models.ContainerPlugin{Image: "sleep"},
+	}))
+	err := r.Run(context.Background(), "sleep", nil)
+	assert.EqualError(t, err, "failed to run command: command timed out after 50ms")
 }
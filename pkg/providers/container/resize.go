@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package container
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize returns a channel which gets a value whenever the terminal of the executor is resized, and
+// a function which stops the notifications.
+func notifyResize() (<-chan os.Signal, func()) {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	return resized, func() { signal.Stop(resized) }
+}
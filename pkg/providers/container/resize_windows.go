@@ -0,0 +1,8 @@
+package container
+
+import "os"
+
+// notifyResize never notifies on Windows, which has no signal for resizing the console.
+func notifyResize() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
@@ -4,12 +4,26 @@ package fakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/Skarlso/providers-example/pkg/models"
 	"github.com/Skarlso/providers-example/pkg/providers"
 )
 
 type FakeStorer struct {
+	ActivateStub        func(context.Context, string, string) error
+	activateMutex       sync.RWMutex
+	activateArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	activateReturns struct {
+		result1 error
+	}
+	activateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	CreateStub        func(context.Context, *models.Plugin) error
 	createMutex       sync.RWMutex
 	createArgsForCall []struct {
@@ -72,10 +86,98 @@ type FakeStorer struct {
 		result1 []*models.Plugin
 		result2 error
 	}
+	RecordRunStub        func(context.Context, string, time.Time) error
+	recordRunMutex       sync.RWMutex
+	recordRunArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 time.Time
+	}
+	recordRunReturns struct {
+		result1 error
+	}
+	recordRunReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UpdateStub        func(context.Context, *models.Plugin) error
+	updateMutex       sync.RWMutex
+	updateArgsForCall []struct {
+		arg1 context.Context
+		arg2 *models.Plugin
+	}
+	updateReturns struct {
+		result1 error
+	}
+	updateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeStorer) Activate(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.activateMutex.Lock()
+	ret, specificReturn := fake.activateReturnsOnCall[len(fake.activateArgsForCall)]
+	fake.activateArgsForCall = append(fake.activateArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ActivateStub
+	fakeReturns := fake.activateReturns
+	fake.recordInvocation("Activate", []interface{}{arg1, arg2, arg3})
+	fake.activateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStorer) ActivateCallCount() int {
+	fake.activateMutex.RLock()
+	defer fake.activateMutex.RUnlock()
+	return len(fake.activateArgsForCall)
+}
+
+func (fake *FakeStorer) ActivateCalls(stub func(context.Context, string, string) error) {
+	fake.activateMutex.Lock()
+	defer fake.activateMutex.Unlock()
+	fake.ActivateStub = stub
+}
+
+func (fake *FakeStorer) ActivateArgsForCall(i int) (context.Context, string, string) {
+	fake.activateMutex.RLock()
+	defer fake.activateMutex.RUnlock()
+	argsForCall := fake.activateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeStorer) ActivateReturns(result1 error) {
+	fake.activateMutex.Lock()
+	defer fake.activateMutex.Unlock()
+	fake.ActivateStub = nil
+	fake.activateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorer) ActivateReturnsOnCall(i int, result1 error) {
+	fake.activateMutex.Lock()
+	defer fake.activateMutex.Unlock()
+	fake.ActivateStub = nil
+	if fake.activateReturnsOnCall == nil {
+		fake.activateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.activateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeStorer) Create(arg1 context.Context, arg2 *models.Plugin) error {
 	fake.createMutex.Lock()
 	ret, specificReturn := fake.createReturnsOnCall[len(fake.createArgsForCall)]
@@ -383,9 +485,136 @@ func (fake *FakeStorer) ListReturnsOnCall(i int, result1 []*models.Plugin, resul
 	}{result1, result2}
 }
 
+func (fake *FakeStorer) RecordRun(arg1 context.Context, arg2 string, arg3 time.Time) error {
+	fake.recordRunMutex.Lock()
+	ret, specificReturn := fake.recordRunReturnsOnCall[len(fake.recordRunArgsForCall)]
+	fake.recordRunArgsForCall = append(fake.recordRunArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 time.Time
+	}{arg1, arg2, arg3})
+	stub := fake.RecordRunStub
+	fakeReturns := fake.recordRunReturns
+	fake.recordInvocation("RecordRun", []interface{}{arg1, arg2, arg3})
+	fake.recordRunMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStorer) RecordRunCallCount() int {
+	fake.recordRunMutex.RLock()
+	defer fake.recordRunMutex.RUnlock()
+	return len(fake.recordRunArgsForCall)
+}
+
+func (fake *FakeStorer) RecordRunCalls(stub func(context.Context, string, time.Time) error) {
+	fake.recordRunMutex.Lock()
+	defer fake.recordRunMutex.Unlock()
+	fake.RecordRunStub = stub
+}
+
+func (fake *FakeStorer) RecordRunArgsForCall(i int) (context.Context, string, time.Time) {
+	fake.recordRunMutex.RLock()
+	defer fake.recordRunMutex.RUnlock()
+	argsForCall := fake.recordRunArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeStorer) RecordRunReturns(result1 error) {
+	fake.recordRunMutex.Lock()
+	defer fake.recordRunMutex.Unlock()
+	fake.RecordRunStub = nil
+	fake.recordRunReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorer) RecordRunReturnsOnCall(i int, result1 error) {
+	fake.recordRunMutex.Lock()
+	defer fake.recordRunMutex.Unlock()
+	fake.RecordRunStub = nil
+	if fake.recordRunReturnsOnCall == nil {
+		fake.recordRunReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.recordRunReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorer) Update(arg1 context.Context, arg2 *models.Plugin) error {
+	fake.updateMutex.Lock()
+	ret, specificReturn := fake.updateReturnsOnCall[len(fake.updateArgsForCall)]
+	fake.updateArgsForCall = append(fake.updateArgsForCall, struct {
+		arg1 context.Context
+		arg2 *models.Plugin
+	}{arg1, arg2})
+	stub := fake.UpdateStub
+	fakeReturns := fake.updateReturns
+	fake.recordInvocation("Update", []interface{}{arg1, arg2})
+	fake.updateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStorer) UpdateCallCount() int {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	return len(fake.updateArgsForCall)
+}
+
+func (fake *FakeStorer) UpdateCalls(stub func(context.Context, *models.Plugin) error) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = stub
+}
+
+func (fake *FakeStorer) UpdateArgsForCall(i int) (context.Context, *models.Plugin) {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	argsForCall := fake.updateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeStorer) UpdateReturns(result1 error) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = nil
+	fake.updateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorer) UpdateReturnsOnCall(i int, result1 error) {
+	fake.updateMutex.Lock()
+	defer fake.updateMutex.Unlock()
+	fake.UpdateStub = nil
+	if fake.updateReturnsOnCall == nil {
+		fake.updateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.updateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeStorer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.activateMutex.RLock()
+	defer fake.activateMutex.RUnlock()
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
 	fake.deleteMutex.RLock()
@@ -396,6 +625,10 @@ func (fake *FakeStorer) Invocations() map[string][][]interface{} {
 	defer fake.initMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.recordRunMutex.RLock()
+	defer fake.recordRunMutex.RUnlock()
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
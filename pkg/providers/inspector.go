@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// Checksum states reported by Health.
+const (
+	// ChecksumNotPinned means the plugin has no checksum to verify against.
+	ChecksumNotPinned = "not pinned"
+	// ChecksumMatch means the binary or the local image matches the pinned checksum.
+	ChecksumMatch = "match"
+	// ChecksumMismatch means the binary or the local image differs from the pinned checksum.
+	ChecksumMismatch = "mismatch"
+	// ChecksumUnknown means there was nothing to verify, because the plugin isn't available.
+	ChecksumUnknown = "unknown"
+)
+
+// Health describes whether a plugin can be run on this machine.
+type Health struct {
+	// Source is the resolved path of the binary, or the image reference.
+	Source string `json:"source" yaml:"source"`
+	// Available is true if the binary exists and is executable, or if the image is present locally.
+	Available bool `json:"available" yaml:"available"`
+	// Problem explains why the plugin isn't available.
+	Problem string `json:"problem,omitempty" yaml:"problem,omitempty"`
+	// Digests are the sha256 of the binary, or the ID and repository digests of the image.
+	Digests []string `json:"digests,omitempty" yaml:"digests,omitempty"`
+	// Checksum is one of the Checksum states.
+	Checksum string `json:"checksum" yaml:"checksum"`
+}
+
+// Inspector checks whether a plugin is ready to run without running it.
+type Inspector interface {
+	Inspect(ctx context.Context, plugin *models.Plugin) (*Health, error)
+}
+
+// VerifyChecksum sets the Checksum state of h by comparing its digests with the one the plugin is pinned to.
+func (h *Health) VerifyChecksum(checksum string) {
+	switch {
+	case checksum == "":
+		h.Checksum = ChecksumNotPinned
+	case len(h.Digests) == 0:
+		h.Checksum = ChecksumUnknown
+	default:
+		h.Checksum = ChecksumMismatch
+		for _, d := range h.Digests {
+			if d == checksum {
+				h.Checksum = ChecksumMatch
+				return
+			}
+		}
+	}
+}
+
+// FileDigest returns the sha256 of a file in the form sha256:<hex>.
+func FileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
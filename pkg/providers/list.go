@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// ApplyListOpts filters, sorts and pages plugins according to opts. It is used by storers which
+// can't do this in a query. The plugins have to be in the order they were created in.
+func ApplyListOpts(plugins []*models.Plugin, opts ListOpts) []*models.Plugin {
+	result := make([]*models.Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		if opts.TypeFilter != "" && p.Type != opts.TypeFilter {
+			continue
+		}
+		if opts.NameFilter != "" && !MatchName(opts.NameFilter, p.Name) {
+			continue
+		}
+		if !opts.Selector.Matches(p.Labels) {
+			continue
+		}
+		if opts.ActiveOnly && !p.Active {
+			continue
+		}
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if opts.Descending {
+			a, b = b, a
+		}
+		// ties are broken by ID, so the order is always the same
+		switch opts.SortBy {
+		case SortByName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			if ka, kb := VersionKey(a.Version), VersionKey(b.Version); ka != kb {
+				return ka < kb
+			}
+		case SortByCreated:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case SortByLastRun:
+			if !a.LastRun.Equal(b.LastRun) {
+				return a.LastRun.Before(b.LastRun)
+			}
+		}
+		return a.ID < b.ID
+	})
+
+	// negative values are treated like zero, callers are expected to reject them
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+	if opts.Offset >= len(result) {
+		return nil
+	}
+	result = result[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(result) {
+		result = result[:opts.Limit]
+	}
+	return result
+}
+
+// MatchName returns true if name matches the pattern as described by ListOpts.NameFilter.
+func MatchName(pattern, name string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return strings.Contains(strings.ToLower(name), strings.ToLower(pattern))
+	}
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(name)
+}
@@ -0,0 +1,16 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+func TestApplyListOptsNegativePagination(t *testing.T) {
+	plugins := []*models.Plugin{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	assert.Len(t, ApplyListOpts(plugins, ListOpts{Offset: -1}), 3)
+	assert.Len(t, ApplyListOpts(plugins, ListOpts{Limit: -1, Offset: 1}), 2)
+	assert.Len(t, ApplyListOpts(plugins, ListOpts{Limit: 1, Offset: -5}), 1)
+}
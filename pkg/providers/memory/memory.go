@@ -0,0 +1,357 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Storer keeps plugins in memory. It follows the same rules as the SQLite backed storer,
+// names and versions are unique and plugins are listed in the order they were created. Nothing survives
+// the process, which makes it useful for tests and throwaway runs. Removed plugins are kept in a trash
+// until then, next to the pipelines.
+type Storer struct {
+	Logger zerolog.Logger
+	// TrashRetention is how long removed plugins can be restored for.
+	TrashRetention time.Duration
+
+	lock    sync.RWMutex
+	lastID  int
+	plugins []*models.Plugin
+	trash   []*models.TrashedPlugin
+
+	lastPipelineID int
+	pipelines      []*models.Pipeline
+}
+
+var (
+	_ providers.Storer         = &Storer{}
+	_ providers.Trasher        = &Storer{}
+	_ providers.Transactional  = &Storer{}
+	_ providers.PipelineStorer = &Storer{}
+)
+
+// NewStorer creates an empty in-memory storer.
+func NewStorer(logger zerolog.Logger) *Storer {
+	return &Storer{
+		Logger:         logger,
+		TrashRetention: providers.DefaultTrashRetention,
+	}
+}
+
+// Init is a no-op, there is nothing to bootstrap in memory.
+func (s *Storer) Init() error {
+	return nil
+}
+
+// Create stores a copy of the plugin and assigns it an ID.
+func (s *Storer) Create(ctx context.Context, plugin *models.Plugin) error {
+	s.Logger.Debug().Str("name", plugin.Name).Msg("Creating new plugin...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.find(plugin.Name, plugin.Version) != -1 {
+		return fmt.Errorf("failed to create plugin %q: %w", providers.Ref(plugin.Name, plugin.Version), providers.ErrAlreadyExists)
+	}
+	s.lastID++
+	stored := clone(plugin)
+	stored.ID = s.lastID
+	// the first version of a plugin becomes the active one
+	stored.Active = len(s.versions(plugin.Name)) == 0
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	s.plugins = append(s.plugins, stored)
+	return nil
+}
+
+// Get returns a copy of the referenced plugin.
+func (s *Storer) Get(ctx context.Context, name string) (*models.Plugin, error) {
+	s.Logger.Debug().Str("name", name).Msg("Getting plugin...")
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	i := s.resolve(name)
+	if i == -1 {
+		return nil, fmt.Errorf("failed to get plugin %q: %w", name, providers.ErrNotFound)
+	}
+	return clone(s.plugins[i]), nil
+}
+
+// Update replaces the stored plugin with the same name and version, keeping its ID.
+func (s *Storer) Update(ctx context.Context, plugin *models.Plugin) error {
+	s.Logger.Debug().Str("name", plugin.Name).Msg("Updating plugin...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.find(plugin.Name, plugin.Version)
+	if i == -1 {
+		return fmt.Errorf("failed to update plugin %q: %w", providers.Ref(plugin.Name, plugin.Version), providers.ErrNotFound)
+	}
+	stored := clone(plugin)
+	stored.ID = s.plugins[i].ID
+	stored.Active = s.plugins[i].Active
+	stored.CreatedAt = s.plugins[i].CreatedAt
+	stored.LastRun = s.plugins[i].LastRun
+	s.plugins[i] = stored
+	return nil
+}
+
+// RecordRun saves the time the plugin was last run at.
+func (s *Storer) RecordRun(ctx context.Context, name string, at time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.resolve(name)
+	if i == -1 {
+		return fmt.Errorf("failed to record run of plugin %q: %w", name, providers.ErrNotFound)
+	}
+	s.plugins[i].LastRun = at.UTC()
+	return nil
+}
+
+// Delete moves a single version of a plugin or all of them to the trash. If the active version is removed,
+// the latest remaining one becomes active. Removing a plugin which doesn't exist is not an error.
+func (s *Storer) Delete(ctx context.Context, name string) error {
+	s.Logger.Debug().Str("name", name).Msg("Deleting plugin...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now().UTC()
+	name, version, pinned := providers.ParseRef(name)
+	kept := s.plugins[:0]
+	for _, p := range s.plugins {
+		if p.Name != name || pinned && p.Version != version {
+			kept = append(kept, p)
+			continue
+		}
+		// only the most recently removed plugin of a name and version is kept
+		s.removeTrashed(p.Name, p.Version)
+		s.trash = append(s.trash, &models.TrashedPlugin{Plugin: p, DeletedAt: now})
+	}
+	s.plugins = kept
+	s.purge(now.Add(-s.TrashRetention))
+	if remaining := s.versions(name); len(remaining) > 0 && s.active(name) == -1 {
+		providers.Latest(remaining).Active = true
+	}
+	return nil
+}
+
+// Activate makes the given version the active version of the plugin.
+func (s *Storer) Activate(ctx context.Context, name, version string) error {
+	s.Logger.Debug().Str("name", name).Str("version", version).Msg("Activating plugin...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.find(name, version)
+	if i == -1 {
+		return fmt.Errorf("failed to activate plugin %q: %w", providers.Ref(name, version), providers.ErrNotFound)
+	}
+	for _, p := range s.versions(name) {
+		p.Active = false
+	}
+	s.plugins[i].Active = true
+	return nil
+}
+
+// List returns copies of all plugins matching the given options.
+func (s *Storer) List(ctx context.Context, opts providers.ListOpts) ([]*models.Plugin, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]*models.Plugin, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		result = append(result, clone(p))
+	}
+	return providers.ApplyListOpts(result, opts), nil
+}
+
+// ListTrash returns copies of the removed plugins which can still be restored, the most recently removed first.
+func (s *Storer) ListTrash(ctx context.Context) ([]*models.TrashedPlugin, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var result []*models.TrashedPlugin
+	for _, t := range s.restorable() {
+		result = append(result, &models.TrashedPlugin{Plugin: clone(t.Plugin), DeletedAt: t.DeletedAt, ExpiresAt: t.DeletedAt.Add(s.TrashRetention)})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].DeletedAt.Equal(result[j].DeletedAt) {
+			return result[i].DeletedAt.After(result[j].DeletedAt)
+		}
+		return result[i].Plugin.ID > result[j].Plugin.ID
+	})
+	return result, nil
+}
+
+// RestoreTrashed brings back removed plugins. Nothing is restored if any of them has been added again since.
+func (s *Storer) RestoreTrashed(ctx context.Context, ref string) error {
+	s.Logger.Debug().Str("name", ref).Msg("Restoring plugin...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name, version, pinned := providers.ParseRef(ref)
+	var restore []*models.Plugin
+	for _, t := range s.restorable() {
+		if t.Plugin.Name == name && (!pinned || t.Plugin.Version == version) {
+			restore = append(restore, t.Plugin)
+		}
+	}
+	if len(restore) == 0 {
+		return fmt.Errorf("failed to restore plugin %q: %w", ref, providers.ErrNotFound)
+	}
+	for _, p := range restore {
+		if s.find(p.Name, p.Version) != -1 {
+			return fmt.Errorf("failed to restore plugin %q: %w", providers.Ref(p.Name, p.Version), providers.ErrAlreadyExists)
+		}
+	}
+	// in the order they were created in, a restored version only becomes active if the plugin has no other version
+	sort.Slice(restore, func(i, j int) bool {
+		return restore[i].ID < restore[j].ID
+	})
+	for _, p := range restore {
+		s.lastID++
+		p.ID = s.lastID
+		p.Active = len(s.versions(p.Name)) == 0
+		s.plugins = append(s.plugins, p)
+		s.removeTrashed(p.Name, p.Version)
+	}
+	return nil
+}
+
+// PurgeTrash deletes the plugins removed before the given time for good.
+func (s *Storer) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.purge(before), nil
+}
+
+// Transaction runs fn with a copy of the storer, whose plugins, trash and pipelines replace the stored ones once fn
+// returns nil. Other changes wait until then.
+func (s *Storer) Transaction(ctx context.Context, fn func(s providers.Storer) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tx := &Storer{Logger: s.Logger, TrashRetention: s.TrashRetention, lastID: s.lastID, lastPipelineID: s.lastPipelineID}
+	for _, p := range s.plugins {
+		tx.plugins = append(tx.plugins, clone(p))
+	}
+	for _, t := range s.trash {
+		tx.trash = append(tx.trash, &models.TrashedPlugin{Plugin: clone(t.Plugin), DeletedAt: t.DeletedAt})
+	}
+	for _, p := range s.pipelines {
+		tx.pipelines = append(tx.pipelines, clonePipeline(p))
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.lastID, s.plugins, s.trash = tx.lastID, tx.plugins, tx.trash
+	s.lastPipelineID, s.pipelines = tx.lastPipelineID, tx.pipelines
+	return nil
+}
+
+// restorable returns the trashed plugins which haven't expired yet. The caller must hold the lock.
+func (s *Storer) restorable() []*models.TrashedPlugin {
+	after := time.Now().Add(-s.TrashRetention)
+	var result []*models.TrashedPlugin
+	for _, t := range s.trash {
+		if !t.DeletedAt.Before(after) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// removeTrashed drops the given version of a plugin from the trash. The caller must hold the lock.
+func (s *Storer) removeTrashed(name, version string) {
+	kept := s.trash[:0]
+	for _, t := range s.trash {
+		if t.Plugin.Name != name || t.Plugin.Version != version {
+			kept = append(kept, t)
+		}
+	}
+	s.trash = kept
+}
+
+// purge drops the plugins removed before the given time and returns how many there were. The caller must
+// hold the lock.
+func (s *Storer) purge(before time.Time) int {
+	kept := s.trash[:0]
+	for _, t := range s.trash {
+		if !t.DeletedAt.Before(before) {
+			kept = append(kept, t)
+		}
+	}
+	n := len(s.trash) - len(kept)
+	s.trash = kept
+	return n
+}
+
+// find returns the index of the given version of a plugin or -1. The caller must hold the lock.
+func (s *Storer) find(name, version string) int {
+	for i, p := range s.plugins {
+		if p.Name == name && p.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// active returns the index of the active version of a plugin or -1. The caller must hold the lock.
+func (s *Storer) active(name string) int {
+	for i, p := range s.plugins {
+		if p.Name == name && p.Active {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve returns the index of the referenced plugin or -1. The caller must hold the lock.
+func (s *Storer) resolve(ref string) int {
+	name, version, pinned := providers.ParseRef(ref)
+	if pinned {
+		return s.find(name, version)
+	}
+	return s.active(name)
+}
+
+// versions returns all versions of a plugin. The caller must hold the lock.
+func (s *Storer) versions(name string) []*models.Plugin {
+	var result []*models.Plugin
+	for _, p := range s.plugins {
+		if p.Name == name {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// clone makes sure callers can never modify what is stored.
+func clone(plugin *models.Plugin) *models.Plugin {
+	c := *plugin
+	if plugin.Container != nil {
+		container := *plugin.Container
+		c.Container = &container
+	}
+	if plugin.Bare != nil {
+		bare := *plugin.Bare
+		c.Bare = &bare
+	}
+	if plugin.Labels != nil {
+		c.Labels = make(map[string]string, len(plugin.Labels))
+		for k, v := range plugin.Labels {
+			c.Labels[k] = v
+		}
+	}
+	return &c
+}
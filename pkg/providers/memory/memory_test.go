@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storertest"
+)
+
+func TestStorer_MainFlow(t *testing.T) {
+	s := NewStorer(zerolog.New(os.Stderr))
+	ctx := context.Background()
+	err := s.Create(ctx, &models.Plugin{
+		Name: "test-bare-1",
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: "/tmp/plugins",
+		},
+	})
+	assert.NoError(t, err)
+	err = s.Create(ctx, &models.Plugin{
+		Name: "test-container-1",
+		Type: models.Container,
+		Container: &models.ContainerPlugin{
+			Image: "skarlso/container:v0.0.1",
+		},
+	})
+	assert.NoError(t, err)
+
+	// names are unique
+	err = s.Create(ctx, &models.Plugin{Name: "test-bare-1", Type: models.Bare})
+	assert.ErrorIs(t, err, providers.ErrAlreadyExists)
+
+	p1, err := s.Get(ctx, "test-bare-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p1.ID)
+	assert.Equal(t, "/tmp/plugins", p1.Bare.Location)
+	// modifying the result doesn't modify the stored plugin
+	p1.Bare.Location = "/somewhere/else"
+	p1, err = s.Get(ctx, "test-bare-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/plugins", p1.Bare.Location)
+
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, plugins, 2)
+	assert.Equal(t, "test-bare-1", plugins[0].Name)
+	assert.Equal(t, "test-container-1", plugins[1].Name)
+	plugins, err = s.List(ctx, providers.ListOpts{TypeFilter: models.Container})
+	assert.NoError(t, err)
+	assert.Len(t, plugins, 1)
+	assert.Equal(t, "test-container-1", plugins[0].Name)
+
+	err = s.Delete(ctx, "test-bare-1")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "test-bare-1")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func TestStorer_Conformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) providers.Storer {
+		return NewStorer(zerolog.New(os.Stderr))
+	})
+}
+
+func TestStorer_TransactionConformance(t *testing.T) {
+	storertest.RunTransaction(t, func(t *testing.T) providers.Storer {
+		return NewStorer(zerolog.New(os.Stderr))
+	})
+}
+
+func TestStorer_PipelineConformance(t *testing.T) {
+	storertest.RunPipelines(t, func(t *testing.T) providers.Storer {
+		return NewStorer(zerolog.New(os.Stderr))
+	})
+}
+
+func TestStorer_TrashConformance(t *testing.T) {
+	storertest.RunTrash(t, func(t *testing.T, retention time.Duration) providers.Storer {
+		s := NewStorer(zerolog.New(os.Stderr))
+		s.TrashRetention = retention
+		return s
+	})
+}
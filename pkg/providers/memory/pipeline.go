@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// CreatePipeline stores a copy of the pipeline and assigns it an ID.
+func (s *Storer) CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	s.Logger.Debug().Str("name", pipeline.Name).Msg("Creating pipeline...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.findPipeline(pipeline.Name) != -1 {
+		return fmt.Errorf("failed to create pipeline %q: %w", pipeline.Name, providers.ErrAlreadyExists)
+	}
+	s.lastPipelineID++
+	stored := clonePipeline(pipeline)
+	stored.ID = s.lastPipelineID
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	s.pipelines = append(s.pipelines, stored)
+	return nil
+}
+
+// GetPipeline returns a copy of the pipeline with the given name.
+func (s *Storer) GetPipeline(ctx context.Context, name string) (*models.Pipeline, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	i := s.findPipeline(name)
+	if i == -1 {
+		return nil, fmt.Errorf("failed to get pipeline %q: %w", name, providers.ErrNotFound)
+	}
+	return clonePipeline(s.pipelines[i]), nil
+}
+
+// ListPipelines returns copies of all pipelines, sorted by name.
+func (s *Storer) ListPipelines(ctx context.Context) ([]*models.Pipeline, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]*models.Pipeline, 0, len(s.pipelines))
+	for _, p := range s.pipelines {
+		result = append(result, clonePipeline(p))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// DeletePipeline removes a pipeline, the plugins it runs are left alone.
+func (s *Storer) DeletePipeline(ctx context.Context, name string) error {
+	s.Logger.Debug().Str("name", name).Msg("Deleting pipeline...")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.findPipeline(name)
+	if i == -1 {
+		return fmt.Errorf("failed to delete pipeline %q: %w", name, providers.ErrNotFound)
+	}
+	s.pipelines = append(s.pipelines[:i], s.pipelines[i+1:]...)
+	return nil
+}
+
+// findPipeline returns the index of the named pipeline or -1. The caller must hold the lock.
+func (s *Storer) findPipeline(name string) int {
+	for i, p := range s.pipelines {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// clonePipeline makes sure callers can never modify what is stored.
+func clonePipeline(pipeline *models.Pipeline) *models.Pipeline {
+	c := *pipeline
+	c.Steps = nil
+	for _, step := range pipeline.Steps {
+		st := *step
+		st.Args = append([]string(nil), step.Args...)
+		c.Steps = append(c.Steps, &st)
+	}
+	return &c
+}
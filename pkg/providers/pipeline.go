@@ -0,0 +1,19 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// PipelineStorer is implemented by storers which keep pipelines next to the plugins.
+type PipelineStorer interface {
+	// CreatePipeline stores a new pipeline. It returns ErrAlreadyExists if there is one with the same name.
+	CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error
+	// GetPipeline returns the pipeline with the given name, or ErrNotFound.
+	GetPipeline(ctx context.Context, name string) (*models.Pipeline, error)
+	// ListPipelines returns all pipelines, sorted by name.
+	ListPipelines(ctx context.Context) ([]*models.Pipeline, error)
+	// DeletePipeline removes a pipeline, or returns ErrNotFound. The plugins it runs are left alone.
+	DeletePipeline(ctx context.Context, name string) error
+}
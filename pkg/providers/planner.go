@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Plan is what running a plugin would do, worked out without running it.
+type Plan struct {
+	Name      string         `json:"name" yaml:"name"`
+	Version   string         `json:"version,omitempty" yaml:"version,omitempty"`
+	Type      string         `json:"type" yaml:"type"`
+	Bare      *BarePlan      `json:"bare,omitempty" yaml:"bare,omitempty"`
+	Container *ContainerPlan `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
+// BarePlan is the process a bare plugin would be run as. Bare plugins aren't time-limited, the process runs
+// for as long as it takes.
+type BarePlan struct {
+	// Path is the absolute path of the binary.
+	Path string `json:"path" yaml:"path"`
+	// Args are the arguments passed to the binary, without the binary itself.
+	Args []string `json:"args" yaml:"args"`
+	// Env are the names of the environment variables the process gets. Values aren't shown, they may
+	// hold secrets.
+	Env []string `json:"env" yaml:"env"`
+	// Dir is the working directory of the process.
+	Dir string `json:"dir" yaml:"dir"`
+	// Stdin is true if the stdin of the executor is forwarded to the process.
+	Stdin bool `json:"stdin" yaml:"stdin"`
+	// TTY is true if the process is attached to the terminal of the executor.
+	TTY bool `json:"tty" yaml:"tty"`
+}
+
+// ContainerPlan is the container a container plugin would be run in.
+type ContainerPlan struct {
+	Image string `json:"image" yaml:"image"`
+	// Present is true if the image is present locally.
+	Present bool `json:"present" yaml:"present"`
+	// Digest is the repository digest of the local image, or its ID if it has none.
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// Pull says when the image is pulled.
+	Pull string `json:"pull" yaml:"pull"`
+	// Timeout is how long the container may run before it is killed.
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// Config and HostConfig are sent to Docker to create the container.
+	Config     *container.Config     `json:"config" yaml:"config"`
+	HostConfig *container.HostConfig `json:"hostConfig" yaml:"hostConfig"`
+}
+
+// Planner works out what running a plugin would do without running it.
+type Planner interface {
+	Plan(ctx context.Context, name string, args []string) (*Plan, error)
+}
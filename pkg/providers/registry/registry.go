@@ -0,0 +1,333 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/validation"
+)
+
+// Document is the format the registry is exported in. Every version of a plugin is a separate entry.
+type Document struct {
+	Plugins []*models.Plugin `json:"plugins" yaml:"plugins"`
+}
+
+// Mode defines what happens to stored plugins when importing.
+type Mode string
+
+const (
+	// Merge creates plugins which aren't stored yet and updates the ones which differ. Everything else is left alone.
+	Merge Mode = "merge"
+	// Replace merges and also removes every stored plugin which isn't in the document.
+	Replace Mode = "replace"
+)
+
+// Action is a change made by an import.
+type Action string
+
+const (
+	// Created means the plugin wasn't stored yet.
+	Created Action = "created"
+	// Updated means the stored plugin differed from the document.
+	Updated Action = "updated"
+	// Skipped means the stored plugin is the same as in the document.
+	Skipped Action = "skipped"
+	// Deleted means the stored plugin isn't in the document, when replacing.
+	Deleted Action = "deleted"
+	// Activated means the version was made the active version of the plugin.
+	Activated Action = "activated"
+)
+
+// Change is what an import does, or would do, to a single version of a plugin.
+type Change struct {
+	Action  Action `json:"action" yaml:"action"`
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// Export returns every version of every stored plugin.
+func Export(ctx context.Context, store providers.Storer) (*Document, error) {
+	plugins, err := store.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	if plugins == nil {
+		plugins = []*models.Plugin{}
+	}
+	return &Document{Plugins: plugins}, nil
+}
+
+// Importer applies exported documents to a store.
+type Importer struct {
+	Logger zerolog.Logger
+	Storer providers.Storer
+}
+
+// NewImporter creates a new Importer.
+func NewImporter(logger zerolog.Logger, store providers.Storer) *Importer {
+	return &Importer{
+		Logger: logger,
+		Storer: store,
+	}
+}
+
+// RollbackError is returned by Import if a change failed and not every change made before it could be undone,
+// so the store is left partly imported.
+type RollbackError struct {
+	// Err is why the import failed.
+	Err error
+	// Undo are the errors of the changes which couldn't be undone.
+	Undo []error
+}
+
+func (e *RollbackError) Error() string {
+	undo := make([]string, 0, len(e.Undo))
+	for _, err := range e.Undo {
+		undo = append(undo, err.Error())
+	}
+	return fmt.Sprintf("%s, and %d changes couldn't be undone: %s", e.Err, len(e.Undo), strings.Join(undo, "; "))
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// Import applies the document to the store and returns the changes. With dryRun, the changes are only
+// planned. If the store is providers.Transactional, all changes are made in one transaction. Otherwise,
+// if a change fails, the ones already made are undone before the error is returned, and a *RollbackError
+// is returned if that fails too.
+func (i *Importer) Import(ctx context.Context, doc *Document, mode Mode, dryRun bool) ([]*Change, error) {
+	if mode != Merge && mode != Replace {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+	if err := validate(doc); err != nil {
+		return nil, err
+	}
+	if t, ok := i.Storer.(providers.Transactional); ok && !dryRun {
+		var changes []*Change
+		if err := t.Transaction(ctx, func(s providers.Storer) error {
+			var (
+				steps []*step
+				err   error
+			)
+			if changes, steps, err = prepare(ctx, s, doc, mode); err != nil {
+				return err
+			}
+			for _, st := range steps {
+				if st.apply == nil {
+					continue
+				}
+				if err := st.apply(ctx, s); err != nil {
+					return fmt.Errorf("failed to import %s: %w", ref(st.change), err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return changes, nil
+	}
+	changes, steps, err := prepare(ctx, i.Storer, doc, mode)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	var undo []func() error
+	for _, s := range steps {
+		if s.apply == nil {
+			continue
+		}
+		if err := s.apply(ctx, i.Storer); err != nil {
+			err = fmt.Errorf("failed to import %s: %w", ref(s.change), err)
+			if undoErrs := i.rollback(undo); len(undoErrs) > 0 {
+				return nil, &RollbackError{Err: err, Undo: undoErrs}
+			}
+			return nil, err
+		}
+		revert := s.revert
+		undo = append(undo, func() error { return revert(ctx, i.Storer) })
+	}
+	return changes, nil
+}
+
+// rollback undoes the applied steps in reverse order and returns the errors of the ones which failed.
+func (i *Importer) rollback(undo []func() error) []error {
+	var errs []error
+	for j := len(undo) - 1; j >= 0; j-- {
+		if err := undo[j](); err != nil {
+			i.Logger.Error().Err(err).Msg("Failed to undo change while rolling back import.")
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// prepare reads the stored plugins and works out the changes and the steps which make them.
+func prepare(ctx context.Context, s providers.Storer, doc *Document, mode Mode) ([]*Change, []*step, error) {
+	stored, err := s.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	steps, err := plan(doc, stored, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes := make([]*Change, 0, len(steps))
+	for _, st := range steps {
+		changes = append(changes, st.change)
+	}
+	return changes, steps, nil
+}
+
+// validate checks the definition of every plugin in the document, and returns the problems of all of them.
+func validate(doc *Document) error {
+	var problems []string
+	for _, p := range doc.Plugins {
+		if err := validation.Definition(p); err != nil {
+			var errs validation.Errors
+			if !errors.As(err, &errs) {
+				return err
+			}
+			for _, e := range errs {
+				problems = append(problems, fmt.Sprintf("%s: %s", ref(&Change{Name: p.Name, Version: p.Version}), e))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plugins in document: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+type step struct {
+	change *Change
+	apply  func(ctx context.Context, s providers.Storer) error
+	revert func(ctx context.Context, s providers.Storer) error
+}
+
+// plan works out the steps which turn the stored plugins into the document.
+func plan(doc *Document, stored []*models.Plugin, mode Mode) ([]*step, error) {
+	byRef := make(map[string]*models.Plugin, len(stored))
+	// names which have a version, the first version created of any other name becomes active on its own
+	named := make(map[string]bool, len(stored))
+	for _, p := range stored {
+		byRef[providers.Ref(p.Name, p.Version)] = p
+		named[p.Name] = true
+	}
+	inDoc := make(map[string]bool, len(doc.Plugins))
+	var steps, activations []*step
+	for _, p := range doc.Plugins {
+		if p.Name == "" {
+			return nil, fmt.Errorf("plugin without a name in document")
+		}
+		r := providers.Ref(p.Name, p.Version)
+		if inDoc[r] {
+			return nil, fmt.Errorf("plugin %s is in the document more than once", r)
+		}
+		inDoc[r] = true
+		p := p
+		change := &Change{Name: p.Name, Version: p.Version}
+		current, ok := byRef[r]
+		switch {
+		case !ok:
+			change.Action = Created
+			steps = append(steps, &step{
+				change: change,
+				apply:  func(ctx context.Context, s providers.Storer) error { return s.Create(ctx, p) },
+				revert: func(ctx context.Context, s providers.Storer) error { return s.Delete(ctx, r) },
+			})
+		case sameDefinition(current, p):
+			change.Action = Skipped
+			steps = append(steps, &step{change: change})
+		default:
+			change.Action = Updated
+			steps = append(steps, &step{
+				change: change,
+				apply:  func(ctx context.Context, s providers.Storer) error { return s.Update(ctx, p) },
+				revert: func(ctx context.Context, s providers.Storer) error { return s.Update(ctx, current) },
+			})
+		}
+		if p.Active && (ok && !current.Active || !ok && named[p.Name]) {
+			activations = append(activations, activation(p, stored))
+		}
+		named[p.Name] = true
+	}
+	if mode == Replace {
+		for _, p := range stored {
+			r := providers.Ref(p.Name, p.Version)
+			if inDoc[r] {
+				continue
+			}
+			p := p
+			steps = append(steps, &step{
+				change: &Change{Action: Deleted, Name: p.Name, Version: p.Version},
+				apply:  func(ctx context.Context, s providers.Storer) error { return s.Delete(ctx, r) },
+				revert: func(ctx context.Context, s providers.Storer) error { return restore(ctx, s, p) },
+			})
+		}
+	}
+	return append(steps, activations...), nil
+}
+
+// activation makes p the active version and, when undone, activates the version which was active before.
+func activation(p *models.Plugin, stored []*models.Plugin) *step {
+	var previous *models.Plugin
+	for _, s := range stored {
+		if s.Name == p.Name && s.Active {
+			previous = s
+		}
+	}
+	return &step{
+		change: &Change{Action: Activated, Name: p.Name, Version: p.Version},
+		apply:  func(ctx context.Context, s providers.Storer) error { return s.Activate(ctx, p.Name, p.Version) },
+		revert: func(ctx context.Context, s providers.Storer) error {
+			if previous == nil {
+				return nil
+			}
+			return s.Activate(ctx, previous.Name, previous.Version)
+		},
+	}
+}
+
+// restore creates a deleted plugin again, making it active again if it was.
+func restore(ctx context.Context, s providers.Storer, p *models.Plugin) error {
+	if err := s.Create(ctx, p); err != nil {
+		return err
+	}
+	if p.Active {
+		return s.Activate(ctx, p.Name, p.Version)
+	}
+	return nil
+}
+
+// sameDefinition compares everything about two plugins which an import can change. Timestamps are kept
+// by Update, so they don't count.
+func sameDefinition(a, b *models.Plugin) bool {
+	normalize := func(p *models.Plugin) models.Plugin {
+		c := *p
+		c.ID, c.Active, c.CreatedAt, c.LastRun = 0, false, time.Time{}, time.Time{}
+		if len(c.Labels) == 0 {
+			c.Labels = nil
+		}
+		return c
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func ref(c *Change) string {
+	if c.Version == "" {
+		return c.Name
+	}
+	return providers.Ref(c.Name, c.Version)
+}
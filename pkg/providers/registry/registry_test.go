@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/memory"
+)
+
+func plugin(name, version, location string) *models.Plugin {
+	return &models.Plugin{
+		Name:    name,
+		Version: version,
+		Type:    models.Bare,
+		Bare:    &models.BareMetalPlugin{Location: location},
+	}
+}
+
+func newStore(t *testing.T, plugins ...*models.Plugin) *memory.Storer {
+	s := memory.NewStorer(zerolog.New(os.Stderr))
+	for _, p := range plugins {
+		require.NoError(t, s.Create(context.Background(), p))
+	}
+	return s
+}
+
+func refs(t *testing.T, s providers.Storer) []string {
+	plugins, err := s.List(context.Background(), providers.ListOpts{SortBy: providers.SortByName})
+	require.NoError(t, err)
+	var result []string
+	for _, p := range plugins {
+		r := providers.Ref(p.Name, p.Version)
+		if p.Active {
+			r += "*"
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	labelled := plugin("echo", "1.0.0", "/bin/echo")
+	labelled.Labels = map[string]string{"team": "infra"}
+	source := newStore(t, labelled, plugin("echo", "1.1.0", "/bin/echo"), plugin("hello", "", "/bin/hello"))
+	require.NoError(t, source.Activate(ctx, "echo", "1.1.0"))
+
+	doc, err := Export(ctx, source)
+	require.NoError(t, err)
+	assert.Len(t, doc.Plugins, 3)
+
+	target := newStore(t)
+	changes, err := NewImporter(zerolog.New(os.Stderr), target).Import(ctx, doc, Merge, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{Action: Created, Name: "echo", Version: "1.0.0"},
+		{Action: Created, Name: "echo", Version: "1.1.0"},
+		{Action: Created, Name: "hello"},
+		{Action: Activated, Name: "echo", Version: "1.1.0"},
+	}, changes)
+	assert.Equal(t, []string{"echo@1.0.0", "echo@1.1.0*", "hello@*"}, refs(t, target))
+	p, err := target.Get(ctx, "echo@1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "infra", p.Labels["team"])
+
+	// importing again changes nothing
+	changes, err = NewImporter(zerolog.New(os.Stderr), target).Import(ctx, doc, Merge, false)
+	require.NoError(t, err)
+	for _, c := range changes {
+		assert.Equal(t, Skipped, c.Action)
+	}
+}
+
+func TestImportModes(t *testing.T) {
+	ctx := context.Background()
+	doc := &Document{Plugins: []*models.Plugin{
+		plugin("echo", "1.0.0", "/usr/bin/echo"),
+		plugin("new", "", "/bin/new"),
+	}}
+
+	t.Run("merge", func(t *testing.T) {
+		s := newStore(t, plugin("echo", "1.0.0", "/bin/echo"), plugin("other", "", "/bin/other"))
+		changes, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Merge, false)
+		require.NoError(t, err)
+		assert.Equal(t, []*Change{
+			{Action: Updated, Name: "echo", Version: "1.0.0"},
+			{Action: Created, Name: "new"},
+		}, changes)
+		assert.Equal(t, []string{"echo@1.0.0*", "new@*", "other@*"}, refs(t, s))
+		p, err := s.Get(ctx, "echo")
+		require.NoError(t, err)
+		assert.Equal(t, "/usr/bin/echo", p.Bare.Location)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		s := newStore(t, plugin("echo", "1.0.0", "/bin/echo"), plugin("other", "", "/bin/other"))
+		changes, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Replace, false)
+		require.NoError(t, err)
+		assert.Equal(t, []*Change{
+			{Action: Updated, Name: "echo", Version: "1.0.0"},
+			{Action: Created, Name: "new"},
+			{Action: Deleted, Name: "other"},
+		}, changes)
+		assert.Equal(t, []string{"echo@1.0.0*", "new@*"}, refs(t, s))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		s := newStore(t, plugin("echo", "1.0.0", "/bin/echo"), plugin("other", "", "/bin/other"))
+		changes, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Replace, true)
+		require.NoError(t, err)
+		assert.Len(t, changes, 3)
+		assert.Equal(t, []string{"echo@1.0.0*", "other@*"}, refs(t, s))
+		p, err := s.Get(ctx, "echo")
+		require.NoError(t, err)
+		assert.Equal(t, "/bin/echo", p.Bare.Location)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		s := newStore(t)
+		_, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, &Document{Plugins: []*models.Plugin{
+			plugin("echo", "1.0.0", "/bin/echo"),
+			plugin("echo", "1.0.0", "/bin/echo"),
+		}}, Merge, false)
+		assert.Error(t, err)
+		assert.Empty(t, refs(t, s))
+	})
+}
+
+// failingStorer fails to create the plugin with the given name, and to delete anything if failDelete is set.
+// It only has the methods of providers.Storer, so imports into it are undone step by step.
+type failingStorer struct {
+	providers.Storer
+	name       string
+	failDelete bool
+}
+
+func (f *failingStorer) Create(ctx context.Context, plugin *models.Plugin) error {
+	if plugin.Name == f.name {
+		return errors.New("boom")
+	}
+	return f.Storer.Create(ctx, plugin)
+}
+
+func (f *failingStorer) Delete(ctx context.Context, name string) error {
+	if f.failDelete {
+		return errors.New("can't delete")
+	}
+	return f.Storer.Delete(ctx, name)
+}
+
+func TestImportRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s := &failingStorer{
+		Storer: newStore(t, plugin("echo", "1.0.0", "/bin/echo"), plugin("echo", "1.1.0", "/bin/echo"), plugin("other", "", "/bin/other")),
+		name:   "zzz",
+	}
+	active := plugin("echo", "1.1.0", "/usr/bin/echo")
+	active.Active = true
+	doc := &Document{Plugins: []*models.Plugin{
+		plugin("aaa", "", "/bin/aaa"),
+		active,
+		plugin("zzz", "", "/bin/zzz"),
+	}}
+
+	_, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Replace, false)
+	assert.EqualError(t, err, "failed to import zzz: boom")
+	assert.Equal(t, []string{"echo@1.0.0*", "echo@1.1.0", "other@*"}, refs(t, s))
+	p, err := s.Get(ctx, "echo@1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/echo", p.Bare.Location)
+}
+
+func TestImportReportsFailedRollback(t *testing.T) {
+	ctx := context.Background()
+	s := &failingStorer{Storer: newStore(t), name: "zzz", failDelete: true}
+	doc := &Document{Plugins: []*models.Plugin{plugin("aaa", "", "/bin/aaa"), plugin("zzz", "", "/bin/zzz")}}
+
+	_, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Merge, false)
+	var rollbackErr *RollbackError
+	require.True(t, errors.As(err, &rollbackErr))
+	assert.EqualError(t, rollbackErr.Err, "failed to import zzz: boom")
+	assert.EqualError(t, err, "failed to import zzz: boom, and 1 changes couldn't be undone: can't delete")
+	assert.Equal(t, []string{"aaa@*"}, refs(t, s))
+}
+
+func TestImportRejectsInvalidDefinitions(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(t, plugin("echo", "1.0.0", "/bin/echo"))
+	invalid := plugin("../echo", "1.0.0", "/bin/echo")
+	noImage := &models.Plugin{Name: "hello", Type: models.Container}
+	doc := &Document{Plugins: []*models.Plugin{plugin("aaa", "", "/bin/aaa"), invalid, noImage}}
+
+	for _, dryRun := range []bool{true, false} {
+		_, err := NewImporter(zerolog.New(os.Stderr), s).Import(ctx, doc, Replace, dryRun)
+		require.Error(t, err)
+		// every problem is reported at once
+		assert.Contains(t, err.Error(), "../echo@1.0.0: name:")
+		assert.Contains(t, err.Error(), "hello: container.image: is required")
+		assert.Equal(t, []string{"echo@1.0.0*"}, refs(t, s))
+	}
+}
@@ -2,7 +2,9 @@ package providers
 
 import "context"
 
-// Runner runs a plugin.
+// Runner runs a plugin. Every runner passes args to the plugin verbatim, without splitting or quoting them:
+// a bare plugin gets them after the path of its binary, a container plugin as the command of its image,
+// after the image's entrypoint. With no args, a container runs the default command of its image.
 type Runner interface {
 	Run(ctx context.Context, name string, args []string) error
 }
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Operator defines how a Requirement matches a label.
+type Operator string
+
+const (
+	// Equals matches if the label exists and has the given value.
+	Equals Operator = "="
+	// NotEquals matches if the label doesn't exist or has a different value.
+	NotEquals Operator = "!="
+	// Exists matches if the label exists, regardless of its value.
+	Exists Operator = "exists"
+	// NotExists matches if the label doesn't exist.
+	NotExists Operator = "!"
+)
+
+// Requirement is a single condition of a Selector.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Value    string
+}
+
+// Selector selects plugins by their labels. A plugin is selected if it matches all requirements.
+type Selector []Requirement
+
+// ParseSelector parses a comma separated list of requirements, like `team=infra,tier!=prod,beta,!deprecated`.
+func ParseSelector(selector string) (Selector, error) {
+	var result Selector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = Requirement{Key: kv[0], Operator: NotEquals, Value: kv[1]}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			r = Requirement{Key: kv[0], Operator: Equals, Value: kv[1]}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = Requirement{Key: kv[0], Operator: Equals, Value: kv[1]}
+		case strings.HasPrefix(part, "!"):
+			r = Requirement{Key: part[1:], Operator: NotExists}
+		default:
+			r = Requirement{Key: part, Operator: Exists}
+		}
+		r.Key = strings.TrimSpace(r.Key)
+		r.Value = strings.TrimSpace(r.Value)
+		if r.Key == "" {
+			return nil, fmt.Errorf("invalid selector %q: missing label key", part)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// Matches returns true if the labels satisfy every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		value, ok := labels[r.Key]
+		switch r.Operator {
+		case Equals:
+			if !ok || value != r.Value {
+				return false
+			}
+		case NotEquals:
+			if ok && value == r.Value {
+				return false
+			}
+		case Exists:
+			if !ok {
+				return false
+			}
+		case NotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String returns the selector in the format accepted by ParseSelector.
+func (s Selector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, r := range s {
+		switch r.Operator {
+		case Exists:
+			parts = append(parts, r.Key)
+		case NotExists:
+			parts = append(parts, "!"+r.Key)
+		default:
+			parts = append(parts, r.Key+string(r.Operator)+r.Value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseLabels parses a list of key=value pairs. Keys can't contain characters which have
+// a meaning in selectors and values can't contain commas.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if key == "" || strings.ContainsAny(key, "!=, \t") {
+			return nil, fmt.Errorf("invalid label key %q", key)
+		}
+		if strings.Contains(value, ",") {
+			return nil, fmt.Errorf("invalid value for label %q, it can't contain a comma", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatLabels returns labels as a sorted, comma separated list of key=value pairs.
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return strings.Join(parts, ",")
+}
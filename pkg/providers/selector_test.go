@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelector(t *testing.T) {
+	selector, err := ParseSelector("team=infra, tier!=prod,owner==bob,beta,!deprecated")
+	assert.NoError(t, err)
+	assert.Equal(t, Selector{
+		{Key: "team", Operator: Equals, Value: "infra"},
+		{Key: "tier", Operator: NotEquals, Value: "prod"},
+		{Key: "owner", Operator: Equals, Value: "bob"},
+		{Key: "beta", Operator: Exists},
+		{Key: "deprecated", Operator: NotExists},
+	}, selector)
+	assert.Equal(t, "team=infra,tier!=prod,owner=bob,beta,!deprecated", selector.String())
+
+	selector, err = ParseSelector("")
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(nil))
+
+	_, err = ParseSelector("=infra")
+	assert.Error(t, err)
+	_, err = ParseSelector("!")
+	assert.Error(t, err)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"team=infra", "empty=", " tier = prod "})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra", "empty": "", "tier": "prod"}, labels)
+	assert.Equal(t, "empty=,team=infra,tier=prod", FormatLabels(labels))
+
+	for _, invalid := range []string{"team", "=infra", "te am=infra", "!team=infra", "team=a,b"} {
+		_, err := ParseLabels([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
@@ -2,22 +2,65 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Skarlso/providers-example/pkg/models"
 )
 
+var (
+	// ErrNotFound is returned by a Storer when the requested plugin does not exist.
+	ErrNotFound = errors.New("plugin not found")
+	// ErrAlreadyExists is returned by a Storer when a plugin with the same name and version is already stored.
+	ErrAlreadyExists = errors.New("plugin already exists")
+)
+
+// SortField defines what plugins are ordered by when listing them.
+type SortField string
+
+const (
+	// SortByID lists plugins in the order they were created in. This is the default.
+	SortByID SortField = ""
+	// SortByName lists plugins in alphabetical order and the versions of a plugin by semantic version.
+	SortByName SortField = "name"
+	// SortByCreated lists plugins by their creation time.
+	SortByCreated SortField = "created"
+	// SortByLastRun lists plugins by the last time they were run. Plugins which never ran come first.
+	SortByLastRun SortField = "last-run"
+)
+
 // ListOpts defines options for listing plugins.
 type ListOpts struct {
 	TypeFilter string
+	// NameFilter matches plugin names case-insensitively. A pattern containing `*` or `?` is matched
+	// as a glob against the whole name, anything else as a substring.
+	NameFilter string
+	// Selector only lists plugins whose labels match.
+	Selector Selector
+	// ActiveOnly leaves out versions of plugins which aren't active.
+	ActiveOnly bool
+	SortBy   SortField
+	// Descending reverses the order.
+	Descending bool
+	// Limit is the maximum number of plugins returned. Zero means no limit.
+	Limit int
+	// Offset skips the first plugins of the result, used to page through it together with Limit.
+	Offset int
 }
 
-// Storer can store information about the plugins that were created.
+// Storer can store information about the plugins that were created. Every version of a plugin is stored
+// separately and one of them is active. The name passed to Get, RecordRun and Delete is a reference as
+// described by ParseRef.
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 //counterfeiter:generate -o fakes/fake_storer_client.go . Storer
 type Storer interface {
 	Init() error
 	Create(ctx context.Context, plugin *models.Plugin) error
 	Get(ctx context.Context, name string) (*models.Plugin, error)
+	Update(ctx context.Context, plugin *models.Plugin) error
+	RecordRun(ctx context.Context, name string, at time.Time) error
 	Delete(ctx context.Context, name string) error
+	// Activate makes the given version the active version of the plugin.
+	Activate(ctx context.Context, name, version string) error
 	List(ctx context.Context, opts ListOpts) ([]*models.Plugin, error)
 }
@@ -0,0 +1,179 @@
+package storer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// auditColumns are the columns of the audit_log table in the order scanAuditEvent reads them.
+const auditColumns = "id, at, user, action, name, version, before, after"
+
+// audited runs a mutation and records an audit event for every version of a plugin it changed. The versions
+// are read before and after fn, in the same transaction, so the event is only stored if the change is.
+// Activating a version deactivates another one, so every version is read for it, whichever the ref pins.
+func audited(ctx context.Context, q querier, action, ref string, fn func() error) error {
+	name, version, pinned := providers.ParseRef(ref)
+	where, args := "name = $1", []interface{}{name}
+	if pinned && action != models.AuditActivate {
+		where, args = "name = $1 and version = $2", []interface{}{name, version}
+	}
+	versions, err := selectVersions(ctx, q, where, args)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]*models.Plugin, len(versions))
+	for _, v := range versions {
+		p, err := selectPlugin(ctx, q, providers.Ref(name, v))
+		if err != nil {
+			return fmt.Errorf("failed to read plugin for audit log: %w", err)
+		}
+		before[v] = p
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	after, err := selectVersions(ctx, q, where, args)
+	if err != nil {
+		return err
+	}
+	for _, v := range after {
+		if _, ok := before[v]; !ok {
+			versions = append(versions, v)
+		}
+	}
+	at := time.Now()
+	for _, v := range versions {
+		p, err := selectPlugin(ctx, q, providers.Ref(name, v))
+		if errors.Is(err, sql.ErrNoRows) {
+			p = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read plugin for audit log: %w", err)
+		}
+		if p != nil && before[v] != nil && !changed(before[v], p) {
+			continue
+		}
+		if err := insertAuditEvent(ctx, q, &models.AuditEvent{At: at, Action: action, Name: name, Version: v, Before: before[v], After: p}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectVersions returns the versions of the plugins matching the condition.
+func selectVersions(ctx context.Context, q querier, where string, args []interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "select version from plugins where "+where+" order by id;", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read versions: %w", err)
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// changed tells whether a plugin changed, updating or activating a plugin without changing it isn't recorded.
+func changed(before, after *models.Plugin) bool {
+	b, _ := json.Marshal(before)
+	a, _ := json.Marshal(after)
+	return string(a) != string(b)
+}
+
+func insertAuditEvent(ctx context.Context, q querier, e *models.AuditEvent) error {
+	before, err := marshalPlugin(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalPlugin(e.After)
+	if err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "insert into audit_log (at, user, action, name, version, before, after) values ($1, $2, $3, $4, $5, $6, $7);",
+		toUnix(e.At), currentUser(), e.Action, e.Name, e.Version, before, after); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+func selectAuditEvents(ctx context.Context, q querier, opts providers.AuditOpts) ([]*models.AuditEvent, error) {
+	query, args := "select "+auditColumns+" from audit_log where at >= $1", []interface{}{toUnix(opts.Since)}
+	if opts.Ref != "" {
+		name, version, pinned := providers.ParseRef(opts.Ref)
+		query, args = query+" and name = $2", append(args, name)
+		if pinned {
+			query, args = query+" and version = $3", append(args, version)
+		}
+	}
+	rows, err := q.QueryContext(ctx, query+" order by id;", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*models.AuditEvent
+	for rows.Next() {
+		var (
+			e             models.AuditEvent
+			at            int64
+			before, after sql.NullString
+		)
+		if err := rows.Scan(&e.ID, &at, &e.User, &e.Action, &e.Name, &e.Version, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.At = fromUnix(at)
+		if e.Before, err = unmarshalPlugin(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = unmarshalPlugin(after); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func marshalPlugin(p *models.Plugin) (sql.NullString, error) {
+	if p == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode plugin: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func unmarshalPlugin(s sql.NullString) (*models.Plugin, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	p := &models.Plugin{}
+	if err := json.Unmarshal([]byte(s.String), p); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin: %w", err)
+	}
+	return p, nil
+}
+
+// currentUser returns the name of the operating system user running the process.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,21 @@
+package storer
+
+// storeError keeps the message of the underlying database error, but also matches
+// one of the providers sentinel errors, so callers can use errors.Is regardless of
+// which storer they are talking to.
+type storeError struct {
+	err  error
+	kind error
+}
+
+func (e *storeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *storeError) Unwrap() error {
+	return e.err
+}
+
+func (e *storeError) Is(target error) bool {
+	return target == e.kind
+}
@@ -0,0 +1,800 @@
+package storer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+const (
+	fileExtension = ".yaml"
+	lockFile      = ".lock"
+	// trashDir is the directory removed plugins are moved to, named like the files they were removed from.
+	trashDir = ".trash"
+	// defaultLockTimeout is how long a writer waits for the lock before giving up.
+	defaultLockTimeout = 10 * time.Second
+	// staleLockAge is the age after which a lock is considered abandoned by a crashed process.
+	staleLockAge = time.Minute
+)
+
+// pluginDocument is the on-disk format of a single plugin. It is kept separate from models.Plugin
+// so the files stay stable and readable in code reviews.
+type pluginDocument struct {
+	ID          int               `yaml:"id"`
+	Name        string            `yaml:"name"`
+	Version     string            `yaml:"version,omitempty"`
+	Active      bool              `yaml:"active,omitempty"`
+	Type        string            `yaml:"type"`
+	Image       string            `yaml:"image,omitempty"`
+	Location    string            `yaml:"location,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Owner       string            `yaml:"owner,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Checksum    string            `yaml:"checksum,omitempty"`
+	CreatedAt   time.Time         `yaml:"createdAt,omitempty"`
+	LastRun     time.Time         `yaml:"lastRun,omitempty"`
+}
+
+// trashDocument is a removed plugin in the trash directory.
+type trashDocument struct {
+	pluginDocument `yaml:",inline"`
+	DeletedAt      time.Time `yaml:"deletedAt"`
+
+	// path is the file the document was read from.
+	path string
+}
+
+// NewFileStorer creates a storer provider which keeps every plugin in its own YAML file under dir.
+func NewFileStorer(logger zerolog.Logger, dir string) (*FileStorer, error) {
+	f := &FileStorer{Logger: logger, Dir: dir, TrashRetention: providers.DefaultTrashRetention}
+	if err := f.Init(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+var (
+	_ providers.Storer        = &FileStorer{}
+	_ providers.Trasher       = &FileStorer{}
+	_ providers.Transactional = &FileStorer{}
+)
+
+// FileStorer stores plugins as plain YAML files, one per plugin, so a registry can live in a git
+// repository and changes to it can be reviewed as diffs. Files are written to a temporary file first
+// and then renamed, so readers never see a half written plugin. Writers take a lock file in the
+// directory to serialise changes between processes. Removed plugins are moved to the .trash directory.
+type FileStorer struct {
+	Logger zerolog.Logger
+	Dir    string
+	// LockTimeout is how long a writer waits for another writer to finish. Defaults to 10 seconds.
+	LockTimeout time.Duration
+	// TrashRetention is how long removed plugins can be restored for.
+	TrashRetention time.Duration
+
+	// locked is set on the storer handed out by Transaction, which holds the lock already.
+	locked bool
+}
+
+// Init creates the directory if it doesn't exist yet.
+func (f *FileStorer) Init() error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return nil
+}
+
+// Create will create a new entry in our storage.
+func (f *FileStorer) Create(ctx context.Context, plugin *models.Plugin) error {
+	f.Logger.Info().Str("name", plugin.Name).Msg("Creating new plugin...")
+	path, err := f.path(plugin.Name, plugin.Version)
+	if err != nil {
+		return err
+	}
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("failed to create plugin %q: %w", providers.Ref(plugin.Name, plugin.Version), providers.ErrAlreadyExists)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat plugin file: %w", err)
+	}
+	plugins, err := f.readAll()
+	if err != nil {
+		return err
+	}
+	id := 1
+	for _, p := range plugins {
+		if p.ID >= id {
+			id = p.ID + 1
+		}
+	}
+	doc := toDocument(id, plugin)
+	// the first version of a plugin becomes the active one
+	doc.Active = len(versions(plugins, plugin.Name)) == 0
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now().UTC()
+	}
+	if err := f.write(path, doc); err != nil {
+		return err
+	}
+	f.Logger.Info().Str("name", plugin.Name).Msg("done")
+	return nil
+}
+
+// Get returns plugin details.
+func (f *FileStorer) Get(ctx context.Context, name string) (*models.Plugin, error) {
+	f.Logger.Info().Str("name", name).Msg("Getting plugin...")
+	plugins, err := f.readAll()
+	if err != nil {
+		return nil, err
+	}
+	plugin := resolve(plugins, name)
+	if plugin == nil {
+		return nil, fmt.Errorf("failed to get plugin %q: %w", name, providers.ErrNotFound)
+	}
+	return plugin, nil
+}
+
+// Update replaces the stored details of an existing plugin.
+func (f *FileStorer) Update(ctx context.Context, plugin *models.Plugin) error {
+	f.Logger.Info().Str("name", plugin.Name).Msg("Updating plugin...")
+	path, err := f.path(plugin.Name, plugin.Version)
+	if err != nil {
+		return err
+	}
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := f.read(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("failed to update plugin %q: %w", providers.Ref(plugin.Name, plugin.Version), providers.ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	doc := toDocument(stored.ID, plugin)
+	doc.Active = stored.Active
+	doc.CreatedAt = stored.CreatedAt
+	doc.LastRun = stored.LastRun
+	if err := f.write(path, doc); err != nil {
+		return err
+	}
+	f.Logger.Info().Str("name", plugin.Name).Msg("done")
+	return nil
+}
+
+// RecordRun saves the time the plugin was last run at.
+func (f *FileStorer) RecordRun(ctx context.Context, name string, at time.Time) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	plugins, err := f.readAll()
+	if err != nil {
+		return err
+	}
+	plugin := resolve(plugins, name)
+	if plugin == nil {
+		return fmt.Errorf("failed to record run of plugin %q: %w", name, providers.ErrNotFound)
+	}
+	plugin.LastRun = at
+	return f.save(plugin)
+}
+
+// Delete moves a single version of a plugin or all of them to the trash. If the active version
+// is removed, the latest remaining one becomes active.
+func (f *FileStorer) Delete(ctx context.Context, name string) error {
+	f.Logger.Info().Str("name", name).Msg("Deleting plugin...")
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	plugins, err := f.readAll()
+	if err != nil {
+		return err
+	}
+	pluginName, version, pinned := providers.ParseRef(name)
+	now := time.Now().UTC()
+	var remaining []*models.Plugin
+	for _, p := range versions(plugins, pluginName) {
+		if pinned && p.Version != version {
+			remaining = append(remaining, p)
+			continue
+		}
+		if err := f.trash(p, now); err != nil {
+			return err
+		}
+	}
+	if _, err := f.purge(now.Add(-f.TrashRetention)); err != nil {
+		return err
+	}
+	if len(remaining) > 0 && activeOf(remaining) == nil {
+		latest := providers.Latest(remaining)
+		latest.Active = true
+		if err := f.save(latest); err != nil {
+			return err
+		}
+	}
+	f.Logger.Info().Str("name", name).Msg("done")
+	return nil
+}
+
+// Activate makes the given version the active version of the plugin.
+func (f *FileStorer) Activate(ctx context.Context, name, version string) error {
+	f.Logger.Info().Str("name", name).Str("version", version).Msg("Activating plugin...")
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	plugins, err := f.readAll()
+	if err != nil {
+		return err
+	}
+	if find(plugins, name, version) == nil {
+		return fmt.Errorf("failed to activate plugin %q: %w", providers.Ref(name, version), providers.ErrNotFound)
+	}
+	for _, p := range versions(plugins, name) {
+		active := p.Version == version
+		if p.Active == active {
+			continue
+		}
+		p.Active = active
+		if err := f.save(p); err != nil {
+			return err
+		}
+	}
+	f.Logger.Info().Str("name", name).Msg("done")
+	return nil
+}
+
+// List all available plugins.
+func (f *FileStorer) List(ctx context.Context, opts providers.ListOpts) ([]*models.Plugin, error) {
+	plugins, err := f.readAll()
+	if err != nil {
+		return nil, err
+	}
+	return providers.ApplyListOpts(plugins, opts), nil
+}
+
+// ListTrash returns the removed plugins which can still be restored, the most recently removed first.
+func (f *FileStorer) ListTrash(ctx context.Context) ([]*models.TrashedPlugin, error) {
+	docs, err := f.readTrash(time.Now().Add(-f.TrashRetention))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if !docs[i].DeletedAt.Equal(docs[j].DeletedAt) {
+			return docs[i].DeletedAt.After(docs[j].DeletedAt)
+		}
+		return docs[i].ID > docs[j].ID
+	})
+	result := make([]*models.TrashedPlugin, 0, len(docs))
+	for _, d := range docs {
+		result = append(result, &models.TrashedPlugin{
+			Plugin:    d.plugin(),
+			DeletedAt: d.DeletedAt,
+			ExpiresAt: d.DeletedAt.Add(f.TrashRetention),
+		})
+	}
+	return result, nil
+}
+
+// RestoreTrashed brings back removed plugins. Nothing is restored if any of them has been added again since.
+func (f *FileStorer) RestoreTrashed(ctx context.Context, ref string) error {
+	f.Logger.Info().Str("name", ref).Msg("Restoring plugin...")
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	docs, err := f.readTrash(time.Now().Add(-f.TrashRetention))
+	if err != nil {
+		return err
+	}
+	name, version, pinned := providers.ParseRef(ref)
+	var restore []*trashDocument
+	for _, d := range docs {
+		if d.Name == name && (!pinned || d.Version == version) {
+			restore = append(restore, d)
+		}
+	}
+	if len(restore) == 0 {
+		return fmt.Errorf("failed to restore plugin %q: %w", ref, providers.ErrNotFound)
+	}
+	plugins, err := f.readAll()
+	if err != nil {
+		return err
+	}
+	for _, d := range restore {
+		if find(plugins, d.Name, d.Version) != nil {
+			return fmt.Errorf("failed to restore plugin %q: %w", providers.Ref(d.Name, d.Version), providers.ErrAlreadyExists)
+		}
+	}
+	// in the order they were created in, like the other storers restore them
+	sort.Slice(restore, func(i, j int) bool {
+		return restore[i].ID < restore[j].ID
+	})
+	id := 1
+	for _, p := range plugins {
+		if p.ID >= id {
+			id = p.ID + 1
+		}
+	}
+	for _, d := range restore {
+		path, err := f.path(d.Name, d.Version)
+		if err != nil {
+			return err
+		}
+		doc := d.pluginDocument
+		doc.ID = id
+		id++
+		// a restored version only becomes active if the plugin has no other version
+		doc.Active = len(versions(plugins, d.Name)) == 0
+		if err := f.write(path, &doc); err != nil {
+			return err
+		}
+		plugins = append(plugins, doc.plugin())
+		if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove plugin from trash: %w", err)
+		}
+	}
+	f.Logger.Info().Str("name", ref).Msg("done")
+	return nil
+}
+
+// PurgeTrash deletes the plugins removed before the given time for good.
+func (f *FileStorer) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	f.Logger.Info().Time("before", before).Msg("Purging trash...")
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	n, err := f.purge(before)
+	if err != nil {
+		return 0, err
+	}
+	f.Logger.Info().Int("count", n).Msg("done")
+	return n, nil
+}
+
+// Transaction runs fn with a Storer which makes its changes while holding the lock. If fn fails, the files
+// of the plugins and of the trash are put back the way they were.
+func (f *FileStorer) Transaction(ctx context.Context, fn func(s providers.Storer) error) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	before, err := f.snapshot()
+	if err != nil {
+		return err
+	}
+	tx := &FileStorer{Logger: f.Logger, Dir: f.Dir, TrashRetention: f.TrashRetention, locked: true}
+	if err := fn(tx); err != nil {
+		if rollbackErr := f.rollback(before); rollbackErr != nil {
+			return fmt.Errorf("failed to roll back after %v: %w", err, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// snapshot reads the files of the plugins and of the trash.
+func (f *FileStorer) snapshot() (map[string][]byte, error) {
+	paths, err := f.files()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin file: %w", err)
+		}
+		files[path] = content
+	}
+	return files, nil
+}
+
+// rollback puts back the files of a snapshot and removes the ones which weren't there.
+func (f *FileStorer) rollback(files map[string][]byte) error {
+	paths, err := f.files()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, ok := files[path]; ok {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove plugin file: %w", err)
+		}
+	}
+	for path, content := range files {
+		if err := f.writeFile(path, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// files returns the files of the plugins and of the trash.
+func (f *FileStorer) files() ([]string, error) {
+	var result []string
+	for _, dir := range []string{f.Dir, filepath.Join(f.Dir, trashDir)} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+fileExtension))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list plugin files: %w", err)
+		}
+		for _, path := range paths {
+			if !strings.HasPrefix(filepath.Base(path), ".") {
+				result = append(result, path)
+			}
+		}
+	}
+	return result, nil
+}
+
+// trash moves the file of a plugin into the trash directory, replacing the version removed before.
+func (f *FileStorer) trash(plugin *models.Plugin, at time.Time) error {
+	path, err := f.path(plugin.Name, plugin.Version)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(f.Dir, trashDir), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	doc := &trashDocument{pluginDocument: *toDocument(plugin.ID, plugin), DeletedAt: at}
+	if err := f.write(f.trashPath(path), doc); err != nil {
+		return fmt.Errorf("failed to move plugin to trash: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove plugin file: %w", err)
+	}
+	return nil
+}
+
+// readTrash returns the trashed plugins which were removed at or after the given time.
+func (f *FileStorer) readTrash(after time.Time) ([]*trashDocument, error) {
+	paths, err := filepath.Glob(filepath.Join(f.Dir, trashDir, "*"+fileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash files: %w", err)
+	}
+	var docs []*trashDocument
+	for _, path := range paths {
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		doc := &trashDocument{path: path}
+		if err := yaml.Unmarshal(content, doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if !doc.DeletedAt.Before(after) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// purge deletes the trashed plugins removed before the given time.
+func (f *FileStorer) purge(before time.Time) (int, error) {
+	docs, err := f.readTrash(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, d := range docs {
+		if !d.DeletedAt.Before(before) {
+			continue
+		}
+		if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+			return n, fmt.Errorf("failed to purge trash: %w", err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// trashPath returns where the file of a plugin is kept once it's removed.
+func (f *FileStorer) trashPath(path string) string {
+	return filepath.Join(f.Dir, trashDir, filepath.Base(path))
+}
+
+// path returns the file of a version of a plugin, refusing names which would end up outside of the directory.
+// Plugins without a version are stored as <name>.yaml, the others as <name>@<version>.yaml.
+func (f *FileStorer) path(name, version string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\@`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid plugin name %q for a file store", name)
+	}
+	if strings.ContainsAny(version, `/\`) {
+		return "", fmt.Errorf("invalid plugin version %q for a file store", version)
+	}
+	if version == "" {
+		return filepath.Join(f.Dir, name+fileExtension), nil
+	}
+	return filepath.Join(f.Dir, providers.Ref(name, version)+fileExtension), nil
+}
+
+// save writes a plugin read by readAll back to its file.
+func (f *FileStorer) save(plugin *models.Plugin) error {
+	path, err := f.path(plugin.Name, plugin.Version)
+	if err != nil {
+		return err
+	}
+	return f.write(path, toDocument(plugin.ID, plugin))
+}
+
+func (f *FileStorer) read(path string) (*pluginDocument, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &pluginDocument{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// readAll returns all stored plugins ordered by ID, which is the order they were created in.
+// Files may be edited by hand, so if none of the versions of a plugin is marked active, the latest one is.
+func (f *FileStorer) readAll() ([]*models.Plugin, error) {
+	paths, err := filepath.Glob(filepath.Join(f.Dir, "*"+fileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugin files: %w", err)
+	}
+	docs := make([]*pluginDocument, 0, len(paths))
+	for _, path := range paths {
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+		doc, err := f.read(path)
+		if os.IsNotExist(err) {
+			// removed since we listed the directory
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].ID < docs[j].ID
+	})
+	plugins := make([]*models.Plugin, 0, len(docs))
+	for _, d := range docs {
+		plugins = append(plugins, d.plugin())
+	}
+	for _, p := range plugins {
+		if vs := versions(plugins, p.Name); activeOf(vs) == nil {
+			providers.Latest(vs).Active = true
+		}
+	}
+	return plugins, nil
+}
+
+// versions returns all versions of the named plugin.
+func versions(plugins []*models.Plugin, name string) []*models.Plugin {
+	var result []*models.Plugin
+	for _, p := range plugins {
+		if p.Name == name {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func activeOf(plugins []*models.Plugin) *models.Plugin {
+	for _, p := range plugins {
+		if p.Active {
+			return p
+		}
+	}
+	return nil
+}
+
+func find(plugins []*models.Plugin, name, version string) *models.Plugin {
+	for _, p := range plugins {
+		if p.Name == name && p.Version == version {
+			return p
+		}
+	}
+	return nil
+}
+
+// resolve returns the plugin a reference refers to, or nil.
+func resolve(plugins []*models.Plugin, ref string) *models.Plugin {
+	name, version, pinned := providers.ParseRef(ref)
+	if pinned {
+		return find(plugins, name, version)
+	}
+	return activeOf(versions(plugins, name))
+}
+
+// write atomically replaces the content of path by writing a temporary file and renaming it.
+func (f *FileStorer) write(path string, doc interface{}) error {
+	content, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin: %w", err)
+	}
+	return f.writeFile(path, content)
+}
+
+// writeFile atomically replaces the content of path.
+func (f *FileStorer) writeFile(path string, content []byte) (err error) {
+	tmp, err := os.CreateTemp(f.Dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+	return nil
+}
+
+// lock takes the directory lock, waiting for other writers to finish. The returned function releases it.
+func (f *FileStorer) lock(ctx context.Context) (func(), error) {
+	if f.locked {
+		return func() {}, nil
+	}
+	path := filepath.Join(f.Dir, lockFile)
+	timeout := f.LockTimeout
+	if timeout == 0 {
+		timeout = defaultLockTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = fmt.Fprintf(l, "%d\n", os.Getpid())
+			_ = l.Close()
+			return func() {
+				if err := os.Remove(path); err != nil {
+					f.Logger.Error().Err(err).Msg("failed to remove lock file")
+				}
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge && f.removeStaleLock(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for lock file " + path)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// removeStaleLock removes the lock file if it's still stale and returns true if it's gone. Waiters which found
+// the same stale lock take turns through a second lock file and check the lock again, so none of them can
+// remove the fresh lock another one took in the meantime.
+func (f *FileStorer) removeStaleLock(path string) bool {
+	breaker := path + ".break"
+	b, err := os.OpenFile(breaker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		// only left behind if a process died right while removing a stale lock
+		if info, err := os.Stat(breaker); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(breaker)
+		}
+		return false
+	}
+	_ = b.Close()
+	defer func() {
+		if err := os.Remove(breaker); err != nil {
+			f.Logger.Error().Err(err).Msg("failed to remove lock file")
+		}
+	}()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true
+	} else if err != nil || time.Since(info.ModTime()) <= staleLockAge {
+		return false
+	}
+	f.Logger.Warn().Str("lock", path).Msg("Removing stale lock file.")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		f.Logger.Error().Err(err).Msg("failed to remove stale lock file")
+		return false
+	}
+	return true
+}
+
+func toDocument(id int, plugin *models.Plugin) *pluginDocument {
+	location, image := pluginSource(plugin)
+	return &pluginDocument{
+		ID:          id,
+		Name:        plugin.Name,
+		Version:     plugin.Version,
+		Active:      plugin.Active,
+		Type:        plugin.Type,
+		Image:       image,
+		Location:    location,
+		Description: plugin.Description,
+		Owner:       plugin.Owner,
+		Labels:      plugin.Labels,
+		Checksum:    plugin.Checksum,
+		CreatedAt:   plugin.CreatedAt.UTC(),
+		LastRun:     plugin.LastRun.UTC(),
+	}
+}
+
+func (d *pluginDocument) plugin() *models.Plugin {
+	plugin := &models.Plugin{
+		ID:          d.ID,
+		Name:        d.Name,
+		Version:     d.Version,
+		Active:      d.Active,
+		Type:        d.Type,
+		Description: d.Description,
+		Owner:       d.Owner,
+		Labels:      d.Labels,
+		Checksum:    d.Checksum,
+		CreatedAt:   d.CreatedAt,
+		LastRun:     d.LastRun,
+	}
+	if d.Image != "" {
+		plugin.Container = &models.ContainerPlugin{
+			Image: d.Image,
+		}
+	} else if d.Location != "" {
+		plugin.Bare = &models.BareMetalPlugin{
+			Location: d.Location,
+		}
+	}
+	return plugin
+}
@@ -0,0 +1,84 @@
+package storer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// Pipelines are kept in their own table, with their steps as JSON. Steps refer to plugins by reference,
+// so removing a plugin doesn't touch the pipelines which run it.
+
+const pipelineColumns = "id, name, description, steps, created_at"
+
+func insertPipeline(ctx context.Context, q querier, pipeline *models.Pipeline) error {
+	steps, err := json.Marshal(pipeline.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to encode steps: %w", err)
+	}
+	createdAt := pipeline.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err = q.ExecContext(ctx, "insert into pipelines (name, description, steps, created_at) values ($1, $2, $3, $4);",
+		pipeline.Name, pipeline.Description, string(steps), toUnix(createdAt))
+	return err
+}
+
+// selectPipeline returns sql.ErrNoRows if there is no pipeline with the name.
+func selectPipeline(ctx context.Context, q querier, name string) (*models.Pipeline, error) {
+	return scanPipeline(q.QueryRowContext(ctx, "select "+pipelineColumns+" from pipelines where name = $1;", name))
+}
+
+func selectPipelines(ctx context.Context, q querier) ([]*models.Pipeline, error) {
+	rows, err := q.QueryContext(ctx, "select "+pipelineColumns+" from pipelines order by name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*models.Pipeline
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// deletePipeline returns sql.ErrNoRows if there is no pipeline with the name.
+func deletePipeline(ctx context.Context, q querier, name string) error {
+	res, err := q.ExecContext(ctx, "delete from pipelines where name = $1;", name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanPipeline(s scanner) (*models.Pipeline, error) {
+	var (
+		p         = &models.Pipeline{}
+		steps     string
+		createdAt int64
+	)
+	if err := s.Scan(&p.ID, &p.Name, &p.Description, &steps, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(steps), &p.Steps); err != nil {
+		return nil, fmt.Errorf("failed to decode steps: %w", err)
+	}
+	p.CreatedAt = fromUnix(createdAt)
+	return p, nil
+}
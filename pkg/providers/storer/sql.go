@@ -0,0 +1,473 @@
+package storer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// The queries below are shared by the SQLite and the PostgreSQL storer. Both understand `$n` placeholders,
+// so only the schema and the way constraint violations are reported differ between the two. SQLite numbers
+// the placeholders in the order they appear in, so they have to appear in increasing order.
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const pluginColumns = "id, name, version, active, type, location, image, description, owner, checksum, created_at, last_run"
+
+// sortColumns maps the supported sort fields to columns. Only these are ever put into an order by clause.
+var sortColumns = map[providers.SortField][]string{
+	providers.SortByID:      {"id"},
+	providers.SortByName:    {"name", "version_key"},
+	providers.SortByCreated: {"created_at"},
+	providers.SortByLastRun: {"last_run"},
+}
+
+// withTx runs fn in a transaction which is committed if fn succeeds and rolled back otherwise.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertPlugin stores a new version of a plugin. The first version of a plugin becomes the active one.
+func insertPlugin(ctx context.Context, q querier, plugin *models.Plugin) error {
+	location, image := pluginSource(plugin)
+	createdAt := plugin.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var versions int
+	if err := q.QueryRowContext(ctx, "select count(*) from plugins where name = $1;", plugin.Name).Scan(&versions); err != nil {
+		return fmt.Errorf("failed to count versions: %w", err)
+	}
+	var id int
+	if err := q.QueryRowContext(ctx, "insert into plugins(name, version, version_key, active, type, location, image, description, owner, checksum, created_at, last_run) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) returning id;",
+		plugin.Name, plugin.Version, providers.VersionKey(plugin.Version), toActive(versions == 0), plugin.Type, location, image, plugin.Description, plugin.Owner, plugin.Checksum, toUnix(createdAt), toUnix(plugin.LastRun)).Scan(&id); err != nil {
+		return err
+	}
+	return insertLabels(ctx, q, id, plugin.Labels)
+}
+
+func insertLabels(ctx context.Context, q querier, id int, labels map[string]string) error {
+	for k, v := range labels {
+		if _, err := q.ExecContext(ctx, "insert into plugin_labels(plugin_id, key, value) values($1, $2, $3);", id, k, v); err != nil {
+			return fmt.Errorf("failed to insert label %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// selectPlugin returns the plugin a reference refers to.
+func selectPlugin(ctx context.Context, q querier, ref string) (*models.Plugin, error) {
+	where, args := refCondition(ref)
+	plugin, err := scanPlugin(q.QueryRowContext(ctx, "select "+pluginColumns+" from plugins where "+where+";", args...))
+	if err != nil {
+		return nil, err
+	}
+	if err := selectLabels(ctx, q, []*models.Plugin{plugin}); err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// updatePlugin replaces the definition and labels of a version of a plugin, but leaves its creation and last run
+// time and whether it's active alone. It returns sql.ErrNoRows if there is no plugin to update.
+func updatePlugin(ctx context.Context, q querier, plugin *models.Plugin) error {
+	location, image := pluginSource(plugin)
+	var id int
+	if err := q.QueryRowContext(ctx, "update plugins set type = $1, location = $2, image = $3, description = $4, owner = $5, checksum = $6 where name = $7 and version = $8 returning id;",
+		plugin.Type, location, image, plugin.Description, plugin.Owner, plugin.Checksum, plugin.Name, plugin.Version).Scan(&id); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "delete from plugin_labels where plugin_id = $1;", id); err != nil {
+		return fmt.Errorf("failed to delete labels: %w", err)
+	}
+	return insertLabels(ctx, q, id, plugin.Labels)
+}
+
+// recordRun returns sql.ErrNoRows if there is no such plugin.
+func recordRun(ctx context.Context, q querier, ref string, at time.Time) error {
+	name, version, pinned := providers.ParseRef(ref)
+	query, args := "update plugins set last_run = $1 where name = $2 and active = 1;", []interface{}{toUnix(at), name}
+	if pinned {
+		query, args = "update plugins set last_run = $1 where name = $2 and version = $3;", []interface{}{toUnix(at), name, version}
+	}
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// deletePlugin removes a single version of a plugin or all of them. If the active version is removed,
+// the latest remaining one becomes active.
+func deletePlugin(ctx context.Context, q querier, ref string) error {
+	name, version, pinned := providers.ParseRef(ref)
+	where, args := "name = $1", []interface{}{name}
+	if pinned {
+		where, args = "name = $1 and version = $2", []interface{}{name, version}
+	}
+	if _, err := q.ExecContext(ctx, "delete from plugin_labels where plugin_id in (select id from plugins where "+where+");", args...); err != nil {
+		return fmt.Errorf("failed to delete labels: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, "delete from plugins where "+where+";", args...); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "update plugins set active = 1 where id = (select id from plugins where name = $1 order by version_key desc, id desc limit 1) "+
+		"and not exists (select 1 from plugins where name = $1 and active = 1);", name); err != nil {
+		return fmt.Errorf("failed to activate latest version: %w", err)
+	}
+	return nil
+}
+
+// activatePlugin makes a version of a plugin the active one. It returns sql.ErrNoRows if there is no such version.
+func activatePlugin(ctx context.Context, q querier, name, version string) error {
+	var id int
+	if err := q.QueryRowContext(ctx, "select id from plugins where name = $1 and version = $2;", name, version).Scan(&id); err != nil {
+		return err
+	}
+	// deactivate first, the unique index allows only one active version per name
+	if _, err := q.ExecContext(ctx, "update plugins set active = 0 where name = $1 and active = 1;", name); err != nil {
+		return fmt.Errorf("failed to deactivate versions: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, "update plugins set active = 1 where id = $1;", id); err != nil {
+		return fmt.Errorf("failed to activate version: %w", err)
+	}
+	return nil
+}
+
+// refCondition returns the where condition and its arguments selecting the plugin a reference refers to.
+func refCondition(ref string) (string, []interface{}) {
+	name, version, pinned := providers.ParseRef(ref)
+	if pinned {
+		return "name = $1 and version = $2", []interface{}{name, version}
+	}
+	return "name = $1 and active = 1", []interface{}{name}
+}
+
+func selectPlugins(ctx context.Context, q querier, opts providers.ListOpts) ([]*models.Plugin, error) {
+	query, args, err := listQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*models.Plugin
+	for rows.Next() {
+		plugin, err := scanPlugin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		result = append(result, plugin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	if err := selectLabels(ctx, q, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// listQuery builds the query for listing plugins. User input only ever ends up in the arguments.
+func listQuery(opts providers.ListOpts) (string, []interface{}, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if opts.TypeFilter != "" {
+		where = append(where, "type = "+arg(opts.TypeFilter))
+	}
+	if opts.NameFilter != "" {
+		where = append(where, "lower(name) like "+arg(likePattern(opts.NameFilter))+` escape '\'`)
+	}
+	if opts.ActiveOnly {
+		where = append(where, "active = 1")
+	}
+	for _, r := range opts.Selector {
+		label := "select 1 from plugin_labels where plugin_labels.plugin_id = plugins.id and plugin_labels.key = " + arg(r.Key)
+		switch r.Operator {
+		case providers.Equals:
+			where = append(where, "exists ("+label+" and plugin_labels.value = "+arg(r.Value)+")")
+		case providers.NotEquals:
+			where = append(where, "not exists ("+label+" and plugin_labels.value = "+arg(r.Value)+")")
+		case providers.Exists:
+			where = append(where, "exists ("+label+")")
+		case providers.NotExists:
+			where = append(where, "not exists ("+label+")")
+		default:
+			return "", nil, fmt.Errorf("unknown selector operator %q", r.Operator)
+		}
+	}
+	columns, ok := sortColumns[opts.SortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown sort field %q", opts.SortBy)
+	}
+	direction := "asc"
+	if opts.Descending {
+		direction = "desc"
+	}
+	// ties are broken by id, so the order is always the same
+	var order []string
+	for _, c := range append(columns, "id") {
+		order = append(order, c+" "+direction)
+	}
+
+	query := "select " + pluginColumns + " from plugins"
+	if len(where) > 0 {
+		query += " where " + strings.Join(where, " and ")
+	}
+	query += " order by " + strings.Join(order, ", ")
+	if opts.Limit > 0 || opts.Offset > 0 {
+		limit := int64(opts.Limit)
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+		// PostgreSQL rejects a negative offset
+		offset := opts.Offset
+		if offset < 0 {
+			offset = 0
+		}
+		query += " limit " + arg(limit) + " offset " + arg(offset)
+	}
+	return query + ";", args, nil
+}
+
+// likePattern turns a name filter into a lower case pattern for `like`.
+func likePattern(filter string) string {
+	var b strings.Builder
+	glob := strings.ContainsAny(filter, "*?")
+	if !glob {
+		b.WriteString("%")
+	}
+	for _, r := range strings.ToLower(filter) {
+		switch {
+		case r == '\\' || r == '%' || r == '_':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case glob && r == '*':
+			b.WriteRune('%')
+		case glob && r == '?':
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if !glob {
+		b.WriteString("%")
+	}
+	return b.String()
+}
+
+// selectLabels loads the labels of the given plugins.
+func selectLabels(ctx context.Context, q querier, plugins []*models.Plugin) error {
+	if len(plugins) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.Plugin, len(plugins))
+	placeholders := make([]string, 0, len(plugins))
+	args := make([]interface{}, 0, len(plugins))
+	for i, p := range plugins {
+		byID[p.ID] = p
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, p.ID)
+	}
+	rows, err := q.QueryContext(ctx, "select plugin_id, key, value from plugin_labels where plugin_id in ("+strings.Join(placeholders, ", ")+");", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query labels: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id         int
+			key, value string
+		)
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return fmt.Errorf("failed to scan label: %w", err)
+		}
+		p := byID[id]
+		if p.Labels == nil {
+			p.Labels = make(map[string]string)
+		}
+		p.Labels[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate labels: %w", err)
+	}
+	return nil
+}
+
+func scanPlugin(s scanner) (*models.Plugin, error) {
+	var (
+		storedID          int
+		storedName        string
+		storedVersion     string
+		storedActive      int
+		storedType        string
+		storedLocation    string
+		storedImage       string
+		storedDescription string
+		storedOwner       string
+		storedChecksum    string
+		storedCreatedAt   int64
+		storedLastRun     int64
+	)
+	if err := s.Scan(&storedID, &storedName, &storedVersion, &storedActive, &storedType, &storedLocation, &storedImage, &storedDescription, &storedOwner, &storedChecksum, &storedCreatedAt, &storedLastRun); err != nil {
+		return nil, err
+	}
+	plugin := &models.Plugin{
+		ID:          storedID,
+		Name:        storedName,
+		Version:     storedVersion,
+		Active:      storedActive == 1,
+		Type:        storedType,
+		Description: storedDescription,
+		Owner:       storedOwner,
+		Checksum:    storedChecksum,
+		CreatedAt:   fromUnix(storedCreatedAt),
+		LastRun:     fromUnix(storedLastRun),
+	}
+	if storedImage != "" {
+		plugin.Container = &models.ContainerPlugin{
+			Image: storedImage,
+		}
+	} else if storedLocation != "" {
+		plugin.Bare = &models.BareMetalPlugin{
+			Location: storedLocation,
+		}
+	}
+	return plugin, nil
+}
+
+// Times are stored as unix nanoseconds, which both databases can compare and sort the same way.
+// Zero means not set.
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnix(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// toActive converts to the integer the active column holds, which compares the same way in both databases.
+func toActive(active bool) int {
+	if active {
+		return 1
+	}
+	return 0
+}
+
+func pluginSource(plugin *models.Plugin) (location, image string) {
+	if plugin.Container != nil {
+		image = plugin.Container.Image
+	}
+	if plugin.Bare != nil {
+		location = plugin.Bare.Location
+	}
+	return location, image
+}
+
+// translateError wraps driver errors which have a providers sentinel equivalent.
+func translateError(err error, isUniqueViolation func(error) bool) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &storeError{err: err, kind: providers.ErrNotFound}
+	case isUniqueViolation(err):
+		return &storeError{err: err, kind: providers.ErrAlreadyExists}
+	}
+	return err
+}
+
+// migrate brings the schema up to date. Every migration is applied exactly once and in order,
+// the applied versions are recorded in the schema_migrations table. lock, if not empty, is executed
+// at the start of the transaction to keep concurrent processes from migrating at the same time.
+func migrate(ctx context.Context, db *sql.DB, migrations []string, lock string) (err error) {
+	if _, err := db.ExecContext(ctx, "create table if not exists schema_migrations (version integer primary key);"); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if lock != "" {
+		if _, err := tx.ExecContext(ctx, lock); err != nil {
+			return fmt.Errorf("failed to lock migrations table: %w", err)
+		}
+	}
+	version, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for v := version; v < len(migrations); v++ {
+		if _, err := tx.ExecContext(ctx, migrations[v]); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", v+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, "insert into schema_migrations(version) values($1);", v+1); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", v+1, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// schemaVersion returns the number of migrations applied to the database.
+func schemaVersion(ctx context.Context, q querier) (int, error) {
+	var version int
+	if err := q.QueryRowContext(ctx, "select coalesce(max(version), 0) from schema_migrations;").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
@@ -3,33 +3,65 @@ package storer
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
-	// import db engine
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 	"github.com/rs/zerolog"
 
 	"github.com/Skarlso/providers-example/pkg/models"
 	"github.com/Skarlso/providers-example/pkg/providers"
 )
 
+// liteMigrations bootstraps and upgrades the SQLite schema. Never change an existing entry, append a new one.
+var liteMigrations = []string{
+	`create table if not exists plugins (id integer primary key, name text unique, type text, location text, image text);`,
+	`alter table plugins add column created_at integer not null default 0;`,
+	`alter table plugins add column last_run integer not null default 0;`,
+	`create table plugin_labels (plugin_id integer not null, key text not null, value text not null, primary key (plugin_id, key));`,
+	`alter table plugins add column description text not null default '';`,
+	`alter table plugins add column owner text not null default '';`,
+	`alter table plugins add column checksum text not null default '';`,
+	// SQLite can't drop the unique constraint on name, so the table is rebuilt to make name and version unique instead.
+	// Every existing plugin has no version yet and is the active one.
+	`create table plugins_versioned (id integer primary key, name text not null, version text not null default '', version_key text not null default '', active integer not null default 0, type text, location text, image text, created_at integer not null default 0, last_run integer not null default 0, description text not null default '', owner text not null default '', checksum text not null default '', unique (name, version));
+	insert into plugins_versioned (id, name, active, type, location, image, created_at, last_run, description, owner, checksum) select id, name, 1, type, location, image, created_at, last_run, description, owner, checksum from plugins;
+	drop table plugins;
+	alter table plugins_versioned rename to plugins;
+	create unique index plugins_active on plugins (name) where active = 1;`,
+	`create table audit_log (id integer primary key, at integer not null, user text not null, action text not null, name text not null, version text not null, before text, after text);
+	create index audit_log_name on audit_log (name, at);`,
+	`create table trash (id integer primary key, name text not null, version text not null, deleted_at integer not null, plugin text not null);`,
+	`create table pipelines (id integer primary key, name text not null unique, description text not null default '', steps text not null, created_at integer not null default 0);`,
+}
+
 // NewLiteStorer creates a storer provider.
 func NewLiteStorer(logger zerolog.Logger, location string) (*LiteStorer, error) {
-	l := &LiteStorer{Logger: logger, DBLocation: location}
+	l := &LiteStorer{Logger: logger, DBLocation: location, TrashRetention: providers.DefaultTrashRetention}
 	if err := l.Init(); err != nil {
 		return nil, err
 	}
 	return l, nil
 }
 
-var _ providers.Storer = &LiteStorer{}
+var (
+	_ providers.Storer         = &LiteStorer{}
+	_ providers.Auditor        = &LiteStorer{}
+	_ providers.Trasher        = &LiteStorer{}
+	_ providers.PipelineStorer = &LiteStorer{}
+	_ providers.Transactional  = &LiteStorer{}
+)
 
-// LiteStorer stores information in a SQLite backed storage medium.
+// LiteStorer stores information in a SQLite backed storage medium. Every change to a plugin is recorded
+// in an append-only audit log and removed plugins are kept in a trash.
 type LiteStorer struct {
 	Logger     zerolog.Logger
 	DBLocation string
+	// TrashRetention is how long removed plugins can be restored for.
+	TrashRetention time.Duration
 }
 
 // Create will create a new entry in our storage.
@@ -39,23 +71,13 @@ func (l *LiteStorer) Create(ctx context.Context, plugin *models.Plugin) error {
 	if err != nil {
 		return fmt.Errorf("failed to open connection: %w", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			l.Logger.Error().Err(err).Msg("failed to close db connection")
-		}
-	}()
-	var (
-		image, location string
-	)
-	if plugin.Container != nil {
-		image = plugin.Container.Image
-	}
-	if plugin.Bare != nil {
-		location = plugin.Bare.Location
-	}
-	// we could use a transaction here and all the jazz, but this is a blog post project. :)
-	if _, err = db.Exec("insert into plugins(name, type, location, image) values($1, $2, $3, $4);", plugin.Name, plugin.Type, location, image); err != nil {
-		return fmt.Errorf("failed to run insert into: %w", err)
+	defer l.closeConnection(db)
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		return audited(ctx, tx, models.AuditCreate, providers.Ref(plugin.Name, plugin.Version), func() error {
+			return insertPlugin(ctx, tx, plugin)
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to run insert into: %w", translateError(err, isLiteUniqueViolation))
 	}
 	l.Logger.Info().Str("name", plugin.Name).Msg("done")
 	return nil
@@ -68,61 +90,79 @@ func (l *LiteStorer) Get(ctx context.Context, name string) (*models.Plugin, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to open connection: %w", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			l.Logger.Error().Err(err).Msg("failed to close db connection")
-		}
-	}()
-	// we could use a transaction here and all the jazz, but this is a blog post project. :)
-
-	var (
-		storedID       int
-		storedName     string
-		storedType     string
-		storedLocation string
-		storedImage    string
-	)
-	if err := db.QueryRow("select id, name, type, location, image from plugins where name = $1;", name).Scan(
-		&storedID,
-		&storedName,
-		&storedType,
-		&storedLocation,
-		&storedImage,
-	); err != nil {
-		return nil, fmt.Errorf("failed to run get: %w", err)
-	}
-	result := &models.Plugin{
-		ID:   storedID,
-		Name: storedName,
-		Type: storedType,
-	}
-	if storedImage != "" {
-		result.Container = &models.ContainerPlugin{
-			Image: storedImage,
-		}
-	} else if storedLocation != "" {
-		result.Bare = &models.BareMetalPlugin{
-			Location: storedLocation,
-		}
+	defer l.closeConnection(db)
+	result, err := selectPlugin(ctx, db, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run get: %w", translateError(err, isLiteUniqueViolation))
 	}
 	return result, nil
 }
 
-// Delete removes a plugin from storage.
+// Update replaces the stored details of an existing plugin.
+func (l *LiteStorer) Update(ctx context.Context, plugin *models.Plugin) error {
+	l.Logger.Info().Str("name", plugin.Name).Msg("Updating plugin...")
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		return audited(ctx, tx, models.AuditUpdate, providers.Ref(plugin.Name, plugin.Version), func() error {
+			return updatePlugin(ctx, tx, plugin)
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to run update: %w", translateError(err, isLiteUniqueViolation))
+	}
+	l.Logger.Info().Str("name", plugin.Name).Msg("done")
+	return nil
+}
+
+// RecordRun saves the time the plugin was last run at.
+func (l *LiteStorer) RecordRun(ctx context.Context, name string, at time.Time) error {
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := recordRun(ctx, db, name, at); err != nil {
+		return fmt.Errorf("failed to record run: %w", translateError(err, isLiteUniqueViolation))
+	}
+	return nil
+}
+
+// Activate makes the given version the active version of the plugin.
+func (l *LiteStorer) Activate(ctx context.Context, name, version string) error {
+	l.Logger.Info().Str("name", name).Str("version", version).Msg("Activating plugin...")
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		return audited(ctx, tx, models.AuditActivate, providers.Ref(name, version), func() error {
+			return activatePlugin(ctx, tx, name, version)
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to run activate: %w", translateError(err, isLiteUniqueViolation))
+	}
+	l.Logger.Info().Str("name", name).Msg("done")
+	return nil
+}
+
+// Delete moves a plugin to the trash.
 func (l *LiteStorer) Delete(ctx context.Context, name string) error {
 	l.Logger.Info().Str("name", name).Msg("Deleting plugin...")
 	db, err := l.createConnection()
 	if err != nil {
 		return fmt.Errorf("failed to open connection: %w", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			l.Logger.Error().Err(err).Msg("failed to close db connection")
-		}
-	}()
-	// we could use a transaction here and all the jazz, but this is a blog post project. :)
-	if _, err = db.Exec("delete from plugins where name = $1;", name); err != nil {
-		return fmt.Errorf("failed to run insert into: %w", err)
+	defer l.closeConnection(db)
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		return audited(ctx, tx, models.AuditDelete, name, func() error {
+			return trashPlugins(ctx, tx, name, time.Now(), l.TrashRetention)
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to run delete: %w", err)
 	}
 	l.Logger.Info().Str("name", name).Msg("done")
 	return nil
@@ -134,79 +174,355 @@ func (l *LiteStorer) List(ctx context.Context, opts providers.ListOpts) ([]*mode
 	if err != nil {
 		return nil, fmt.Errorf("failed to open connection: %w", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			l.Logger.Error().Err(err).Msg("failed to close db connection")
-		}
-	}()
-	query := "select id, name, type, location, image from plugins"
-	where := make([]interface{}, 0)
-	if opts.TypeFilter != "" {
-		query += " where type=$1"
-		where = append(where, opts.TypeFilter)
+	defer l.closeConnection(db)
+	result, err := selectPlugins(ctx, db, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	return result, nil
+}
+
+// ListTrash returns the removed plugins which can still be restored, the most recently removed first.
+func (l *LiteStorer) ListTrash(ctx context.Context) ([]*models.TrashedPlugin, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
 	}
-	// we could use a transaction here and all the jazz, but this is a blog post project. :)
-	row, err := db.Query(query, where...)
+	defer l.closeConnection(db)
+	result, err := selectTrash(ctx, db, l.TrashRetention)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run query: %w", err)
 	}
-	var result []*models.Plugin
-	for row.Next() {
-		var (
-			storedID       int
-			storedName     string
-			storedType     string
-			storedLocation string
-			storedImage    string
-		)
-		if err := row.Scan(&storedID, &storedName, &storedType, &storedLocation, &storedImage); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		plugin := &models.Plugin{
-			ID:   storedID,
-			Name: storedName,
-			Type: storedType,
-		}
-		if storedImage != "" {
-			plugin.Container = &models.ContainerPlugin{
-				Image: storedImage,
-			}
-		} else if storedLocation != "" {
-			plugin.Bare = &models.BareMetalPlugin{
-				Location: storedLocation,
-			}
-		}
-		result = append(result, plugin)
+	return result, nil
+}
+
+// RestoreTrashed brings back removed plugins.
+func (l *LiteStorer) RestoreTrashed(ctx context.Context, ref string) error {
+	l.Logger.Info().Str("name", ref).Msg("Restoring plugin...")
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		return audited(ctx, tx, models.AuditRestore, ref, func() error {
+			return restoreTrashed(ctx, tx, ref, l.TrashRetention)
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to run restore: %w", translateError(err, isLiteUniqueViolation))
+	}
+	l.Logger.Info().Str("name", ref).Msg("done")
+	return nil
+}
+
+// PurgeTrash deletes the plugins removed before the given time for good.
+func (l *LiteStorer) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	l.Logger.Info().Time("before", before).Msg("Purging trash...")
+	db, err := l.createConnection()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	n, err := purgeTrash(ctx, db, before)
+	if err != nil {
+		return 0, err
+	}
+	l.Logger.Info().Int("count", n).Msg("done")
+	return n, nil
+}
+
+// Transaction runs fn with a Storer whose changes, and their audit events, are all stored or none of them.
+func (l *LiteStorer) Transaction(ctx context.Context, fn func(s providers.Storer) error) error {
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return fn(&txStorer{tx: tx, isUniqueViolation: isLiteUniqueViolation, audit: true, trashRetention: l.TrashRetention})
+	})
+}
+
+// CreatePipeline stores a new pipeline.
+func (l *LiteStorer) CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	l.Logger.Info().Str("name", pipeline.Name).Msg("Creating pipeline...")
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := insertPipeline(ctx, db, pipeline); err != nil {
+		return fmt.Errorf("failed to run insert into: %w", translateError(err, isLiteUniqueViolation))
+	}
+	l.Logger.Info().Str("name", pipeline.Name).Msg("done")
+	return nil
+}
+
+// GetPipeline returns a pipeline by name.
+func (l *LiteStorer) GetPipeline(ctx context.Context, name string) (*models.Pipeline, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	result, err := selectPipeline(ctx, db, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run get: %w", translateError(err, isLiteUniqueViolation))
 	}
 	return result, nil
 }
 
+// ListPipelines returns all pipelines, sorted by name.
+func (l *LiteStorer) ListPipelines(ctx context.Context) ([]*models.Pipeline, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	result, err := selectPipelines(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	return result, nil
+}
+
+// DeletePipeline removes a pipeline.
+func (l *LiteStorer) DeletePipeline(ctx context.Context, name string) error {
+	l.Logger.Info().Str("name", name).Msg("Deleting pipeline...")
+	db, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := deletePipeline(ctx, db, name); err != nil {
+		return fmt.Errorf("failed to run delete: %w", translateError(err, isLiteUniqueViolation))
+	}
+	l.Logger.Info().Str("name", name).Msg("done")
+	return nil
+}
+
+// AuditLog returns the recorded changes made to plugins, oldest first.
+func (l *LiteStorer) AuditLog(ctx context.Context, opts providers.AuditOpts) ([]*models.AuditEvent, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	events, err := selectAuditEvents(ctx, db, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	return events, nil
+}
+
 func (l *LiteStorer) createConnection() (*sql.DB, error) {
-	// check if db exist. If not, bootstrap it.
-	db, err := sql.Open("sqlite3", filepath.Join(l.DBLocation, "provider.db"))
+	// Wait for concurrent writers instead of failing straight away with `database is locked`. Transactions
+	// take the write lock immediately, so two of them can't deadlock upgrading from a read lock.
+	db, err := sql.Open("sqlite3", l.dbPath()+"?_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 	return db, nil
 }
 
-// Init creates and bootstraps the database.
+func (l *LiteStorer) closeConnection(db *sql.DB) {
+	if err := db.Close(); err != nil {
+		l.Logger.Error().Err(err).Msg("failed to close db connection")
+	}
+}
+
+func (l *LiteStorer) dbPath() string {
+	return filepath.Join(l.DBLocation, "provider.db")
+}
+
+// Path returns the database file.
+func (l *LiteStorer) Path() string {
+	return l.dbPath()
+}
+
+// Init creates the database if it doesn't exist yet and applies any missing migrations. An existing database
+// is backed up into the backups folder next to it before it is migrated.
 func (l *LiteStorer) Init() error {
-	if _, err := os.Stat(filepath.Join(l.DBLocation, "provider.db")); err == nil {
-		return nil
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("failed to stat db file: %w", err)
+	_, err := os.Stat(l.dbPath())
+	existed := err == nil
+	db, err := l.createConnection()
+	if err != nil {
+		return err
+	}
+	defer l.closeConnection(db)
+	ctx := context.Background()
+	if existed {
+		version, err := liteSchemaVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if version < len(liteMigrations) {
+			backup := filepath.Join(l.DBLocation, "backups", fmt.Sprintf("provider-%d-%s.db", version, time.Now().UTC().Format("20060102T150405.000000000Z")))
+			if err := os.MkdirAll(filepath.Dir(backup), 0o700); err != nil {
+				return fmt.Errorf("failed to create backups folder: %w", err)
+			}
+			if err := vacuumInto(ctx, db, backup); err != nil {
+				return fmt.Errorf("failed to back up database before migrating: %w", err)
+			}
+			l.Logger.Info().Str("backup", backup).Int("version", version).Msg("Backed up database before migrating.")
+		}
 	}
-	l.Logger.Debug().Str("location", l.DBLocation).Msg("Creating new database...®")
-	db, err := sql.Open("sqlite3", filepath.Join(l.DBLocation, "provider.db"))
+	if err := migrate(ctx, db, liteMigrations, ""); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
+
+// Backup writes a consistent copy of the database to a file which must not exist yet. It is safe to run
+// while other processes are using the database.
+func (l *LiteStorer) Backup(ctx context.Context, out string) error {
+	l.Logger.Info().Str("out", out).Msg("Backing up database...")
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("failed to back up database: %s %w", out, providers.ErrAlreadyExists)
+	}
+	db, err := l.createConnection()
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	if err := vacuumInto(ctx, db, out); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
 	}
-	defer db.Close()
+	l.Logger.Info().Str("out", out).Msg("done")
+	return nil
+}
 
-	sqlStmt := `create table plugins (id integer primary key, name text unique, type text, location text, image text);`
-	if _, err := db.Exec(sqlStmt); err != nil {
-		return fmt.Errorf("failed to execute bootstrap statement: %w", err)
+// Restore replaces the content of the database with a backup and migrates it, if it was made by an older
+// version. Backups of a newer schema than this version knows are refused.
+func (l *LiteStorer) Restore(ctx context.Context, from string) error {
+	l.Logger.Info().Str("from", from).Msg("Restoring database...")
+	if _, err := os.Stat(from); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	src, err := sql.Open("sqlite3", "file:"+from+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer l.closeConnection(src)
+	var tables int
+	if err := src.QueryRowContext(ctx, "select count(*) from sqlite_master where type = 'table' and name = 'plugins';").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s is not a plugin database", from)
+	}
+	version, err := liteSchemaVersion(ctx, src)
+	if err != nil {
+		return err
+	}
+	if version > len(liteMigrations) {
+		return fmt.Errorf("backup has schema version %d, newer than the supported version %d", version, len(liteMigrations))
+	}
+	dst, err := l.createConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(dst)
+	if err := copyDatabase(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	if err := l.Init(); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
 	}
+	l.Logger.Info().Str("from", from).Int("version", version).Msg("done")
 	return nil
 }
+
+// SchemaVersion returns the schema version of the database and the latest version, which Init migrates it
+// to. It doesn't migrate anything itself.
+func (l *LiteStorer) SchemaVersion(ctx context.Context) (int, int, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	version, err := liteSchemaVersion(ctx, db)
+	if err != nil {
+		return 0, 0, err
+	}
+	return version, len(liteMigrations), nil
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns the problems it found, which are none if the
+// database is intact.
+func (l *LiteStorer) IntegrityCheck(ctx context.Context) ([]string, error) {
+	db, err := l.createConnection()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer l.closeConnection(db)
+	rows, err := db.QueryContext(ctx, "pragma integrity_check;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	return problems, nil
+}
+
+// liteSchemaVersion is schemaVersion for databases which might predate the migrations table.
+func liteSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var tables int
+	if err := db.QueryRowContext(ctx, "select count(*) from sqlite_master where type = 'table' and name = 'schema_migrations';").Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	return schemaVersion(ctx, db)
+}
+
+func vacuumInto(ctx context.Context, db *sql.DB, file string) error {
+	_, err := db.ExecContext(ctx, "vacuum into $1;", file)
+	return err
+}
+
+// copyDatabase overwrites dst with src using SQLite's online backup API, which holds the locks needed to
+// leave dst consistent for other connections.
+func copyDatabase(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	return dstConn.Raw(func(d interface{}) error {
+		return srcConn.Raw(func(s interface{}) error {
+			backup, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				_ = backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+func isLiteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
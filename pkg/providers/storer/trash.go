@@ -0,0 +1,146 @@
+package storer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Removed plugins are kept as JSON in the trash table, so nothing which reads the plugins table has to
+// care about them. There is at most one trashed plugin per name and version, the most recently removed one.
+
+// trashPlugins moves the plugins a reference refers to into the trash, dropping any which were removed
+// before the retention period. Like deletePlugin, a plain name refers to all versions.
+func trashPlugins(ctx context.Context, q querier, ref string, at time.Time, retention time.Duration) error {
+	name, version, pinned := providers.ParseRef(ref)
+	where, args := "name = $1", []interface{}{name}
+	if pinned {
+		where, args = "name = $1 and version = $2", []interface{}{name, version}
+	}
+	versions, err := selectVersions(ctx, q, where, args)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		p, err := selectPlugin(ctx, q, providers.Ref(name, v))
+		if err != nil {
+			return fmt.Errorf("failed to read plugin: %w", err)
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to encode plugin: %w", err)
+		}
+		if _, err := q.ExecContext(ctx, "delete from trash where name = $1 and version = $2;", name, v); err != nil {
+			return fmt.Errorf("failed to replace trashed plugin: %w", err)
+		}
+		if _, err := q.ExecContext(ctx, "insert into trash (name, version, deleted_at, plugin) values ($1, $2, $3, $4);", name, v, toUnix(at), string(b)); err != nil {
+			return fmt.Errorf("failed to move plugin to trash: %w", err)
+		}
+	}
+	if _, err := purgeTrash(ctx, q, at.Add(-retention)); err != nil {
+		return err
+	}
+	return deletePlugin(ctx, q, ref)
+}
+
+// selectTrash returns the trashed plugins which haven't expired yet, the most recently removed first.
+// Expired ones are only purged by the next removal.
+func selectTrash(ctx context.Context, q querier, retention time.Duration) ([]*models.TrashedPlugin, error) {
+	rows, err := q.QueryContext(ctx, "select deleted_at, plugin from trash where deleted_at >= $1 order by deleted_at desc, id desc;", toUnix(time.Now().Add(-retention)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*models.TrashedPlugin
+	for rows.Next() {
+		var (
+			deletedAt int64
+			plugin    string
+		)
+		if err := rows.Scan(&deletedAt, &plugin); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed plugin: %w", err)
+		}
+		p := &models.Plugin{}
+		if err := json.Unmarshal([]byte(plugin), p); err != nil {
+			return nil, fmt.Errorf("failed to decode plugin: %w", err)
+		}
+		t := &models.TrashedPlugin{Plugin: p, DeletedAt: fromUnix(deletedAt)}
+		t.ExpiresAt = t.DeletedAt.Add(retention)
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// restoreTrashed stores the trashed plugins a reference refers to again, unless they have expired. It returns
+// sql.ErrNoRows if there are none. A restored version only becomes active if the plugin has no other version.
+func restoreTrashed(ctx context.Context, q querier, ref string, retention time.Duration) error {
+	name, version, pinned := providers.ParseRef(ref)
+	after := toUnix(time.Now().Add(-retention))
+	query, args := "select id, plugin from trash where name = $1 and deleted_at >= $2 order by id;", []interface{}{name, after}
+	if pinned {
+		query, args = "select id, plugin from trash where name = $1 and version = $2 and deleted_at >= $3 order by id;", []interface{}{name, version, after}
+	}
+	ids, plugins, err := selectTrashed(ctx, q, query, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return sql.ErrNoRows
+	}
+	for i, id := range ids {
+		if err := insertPlugin(ctx, q, plugins[i]); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, "delete from trash where id = $1;", id); err != nil {
+			return fmt.Errorf("failed to remove plugin from trash: %w", err)
+		}
+	}
+	return nil
+}
+
+// selectTrashed returns the ids and plugins of the trash rows the query selects.
+func selectTrashed(ctx context.Context, q querier, query string, args []interface{}) ([]int, []*models.Plugin, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var (
+		ids     []int
+		plugins []*models.Plugin
+	)
+	for rows.Next() {
+		var (
+			id     int
+			plugin string
+		)
+		if err := rows.Scan(&id, &plugin); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan trashed plugin: %w", err)
+		}
+		p := &models.Plugin{}
+		if err := json.Unmarshal([]byte(plugin), p); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode plugin: %w", err)
+		}
+		ids = append(ids, id)
+		plugins = append(plugins, p)
+	}
+	return ids, plugins, rows.Err()
+}
+
+// purgeTrash deletes the plugins trashed before the given time.
+func purgeTrash(ctx context.Context, q querier, before time.Time) (int, error) {
+	res, err := q.ExecContext(ctx, "delete from trash where deleted_at < $1;", toUnix(before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,102 @@
+package storer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+var _ providers.Storer = &txStorer{}
+
+// txStorer is the Storer the SQL storers hand out from Transaction. It makes the same changes as they do,
+// only inside the transaction.
+type txStorer struct {
+	tx                *sql.Tx
+	isUniqueViolation func(error) bool
+	// audit is true if changes are recorded in the audit log.
+	audit          bool
+	trashRetention time.Duration
+}
+
+// change runs fn, recording it in the audit log if the storer keeps one.
+func (s *txStorer) change(ctx context.Context, action, ref string, fn func() error) error {
+	if !s.audit {
+		return fn()
+	}
+	return audited(ctx, s.tx, action, ref, fn)
+}
+
+// Init does nothing, the storer the transaction belongs to is initialised already.
+func (s *txStorer) Init() error {
+	return nil
+}
+
+// Create will create a new entry in our storage.
+func (s *txStorer) Create(ctx context.Context, plugin *models.Plugin) error {
+	if err := s.change(ctx, models.AuditCreate, providers.Ref(plugin.Name, plugin.Version), func() error {
+		return insertPlugin(ctx, s.tx, plugin)
+	}); err != nil {
+		return fmt.Errorf("failed to run insert into: %w", translateError(err, s.isUniqueViolation))
+	}
+	return nil
+}
+
+// Get returns plugin details.
+func (s *txStorer) Get(ctx context.Context, name string) (*models.Plugin, error) {
+	result, err := selectPlugin(ctx, s.tx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run get: %w", translateError(err, s.isUniqueViolation))
+	}
+	return result, nil
+}
+
+// Update replaces the stored details of an existing plugin.
+func (s *txStorer) Update(ctx context.Context, plugin *models.Plugin) error {
+	if err := s.change(ctx, models.AuditUpdate, providers.Ref(plugin.Name, plugin.Version), func() error {
+		return updatePlugin(ctx, s.tx, plugin)
+	}); err != nil {
+		return fmt.Errorf("failed to run update: %w", translateError(err, s.isUniqueViolation))
+	}
+	return nil
+}
+
+// RecordRun saves the time the plugin was last run at.
+func (s *txStorer) RecordRun(ctx context.Context, name string, at time.Time) error {
+	if err := recordRun(ctx, s.tx, name, at); err != nil {
+		return fmt.Errorf("failed to record run: %w", translateError(err, s.isUniqueViolation))
+	}
+	return nil
+}
+
+// Activate makes the given version the active version of the plugin.
+func (s *txStorer) Activate(ctx context.Context, name, version string) error {
+	if err := s.change(ctx, models.AuditActivate, providers.Ref(name, version), func() error {
+		return activatePlugin(ctx, s.tx, name, version)
+	}); err != nil {
+		return fmt.Errorf("failed to run activate: %w", translateError(err, s.isUniqueViolation))
+	}
+	return nil
+}
+
+// Delete moves a plugin to the trash.
+func (s *txStorer) Delete(ctx context.Context, name string) error {
+	if err := s.change(ctx, models.AuditDelete, name, func() error {
+		return trashPlugins(ctx, s.tx, name, time.Now(), s.trashRetention)
+	}); err != nil {
+		return fmt.Errorf("failed to run delete: %w", err)
+	}
+	return nil
+}
+
+// List all available plugins.
+func (s *txStorer) List(ctx context.Context, opts providers.ListOpts) ([]*models.Plugin, error) {
+	result, err := selectPlugins(ctx, s.tx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package storertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// RunPipelines runs the conformance suite for storers which implement providers.PipelineStorer.
+func RunPipelines(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s providers.PipelineStorer)
+	}{
+		{name: "create and get", test: testPipelineCreateGet},
+		{name: "create duplicate", test: testPipelineCreateDuplicate},
+		{name: "list sorted", test: testPipelineList},
+		{name: "delete", test: testPipelineDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := factory(t).(providers.PipelineStorer)
+			require.True(t, ok, "storer doesn't implement providers.PipelineStorer")
+			tt.test(t, s)
+		})
+	}
+}
+
+func pipeline(name string, plugins ...string) *models.Pipeline {
+	p := &models.Pipeline{Name: name}
+	for _, plugin := range plugins {
+		p.Steps = append(p.Steps, &models.PipelineStep{Plugin: plugin})
+	}
+	return p
+}
+
+func testPipelineCreateGet(t *testing.T, s providers.PipelineStorer) {
+	ctx := context.Background()
+	p := pipeline("etl", "extract@1.0.0", "load")
+	p.Description = "extracts and loads"
+	p.Steps[0].Args = []string{"--from", "a, b"}
+	before := time.Now()
+	require.NoError(t, s.CreatePipeline(ctx, p))
+
+	got, err := s.GetPipeline(ctx, "etl")
+	require.NoError(t, err)
+	assert.NotZero(t, got.ID)
+	assert.Equal(t, "extracts and loads", got.Description)
+	assert.Equal(t, []*models.PipelineStep{
+		{Plugin: "extract@1.0.0", Args: []string{"--from", "a, b"}},
+		{Plugin: "load"},
+	}, got.Steps)
+	assert.WithinDuration(t, before, got.CreatedAt, 5*time.Second)
+
+	_, err = s.GetPipeline(ctx, "missing")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func testPipelineCreateDuplicate(t *testing.T, s providers.PipelineStorer) {
+	ctx := context.Background()
+	require.NoError(t, s.CreatePipeline(ctx, pipeline("etl", "extract")))
+	assert.ErrorIs(t, s.CreatePipeline(ctx, pipeline("etl", "load")), providers.ErrAlreadyExists)
+}
+
+func testPipelineList(t *testing.T, s providers.PipelineStorer) {
+	ctx := context.Background()
+	for _, name := range []string{"b", "a", "C"} {
+		require.NoError(t, s.CreatePipeline(ctx, pipeline(name, "extract")))
+	}
+	pipelines, err := s.ListPipelines(ctx)
+	require.NoError(t, err)
+	var got []string
+	for _, p := range pipelines {
+		got = append(got, p.Name)
+	}
+	assert.Equal(t, []string{"C", "a", "b"}, got)
+}
+
+func testPipelineDelete(t *testing.T, s providers.PipelineStorer) {
+	ctx := context.Background()
+	require.NoError(t, s.CreatePipeline(ctx, pipeline("etl", "extract")))
+	require.NoError(t, s.DeletePipeline(ctx, "etl"))
+	_, err := s.GetPipeline(ctx, "etl")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	assert.ErrorIs(t, s.DeletePipeline(ctx, "etl"), providers.ErrNotFound)
+}
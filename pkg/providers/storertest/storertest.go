@@ -0,0 +1,573 @@
+package storertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// Factory returns a new, empty and initialised Storer. It is called once for every test case.
+type Factory func(t *testing.T) providers.Storer
+
+// Run runs the conformance suite against storers created by the factory. Every Storer
+// implementation should call this from its tests to make sure all backends behave the same.
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s providers.Storer)
+	}{
+		{name: "create and get", test: testCreateAndGet},
+		{name: "duplicate names", test: testDuplicateNames},
+		{name: "get not found", test: testGetNotFound},
+		{name: "update", test: testUpdate},
+		{name: "update not found", test: testUpdateNotFound},
+		{name: "delete", test: testDelete},
+		{name: "list", test: testList},
+		{name: "list filters", test: testListFilters},
+		{name: "list name filter", test: testListNameFilter},
+		{name: "list selector", test: testListSelector},
+		{name: "list sorting", test: testListSorting},
+		{name: "list pagination", test: testListPagination},
+		{name: "labels", test: testLabels},
+		{name: "record run", test: testRecordRun},
+		{name: "versions", test: testVersions},
+		{name: "activate", test: testActivate},
+		{name: "delete versions", test: testDeleteVersions},
+		{name: "concurrent access", test: testConcurrentAccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, factory(t))
+		})
+	}
+}
+
+func barePlugin(name string) *models.Plugin {
+	return &models.Plugin{
+		Name: name,
+		Type: models.Bare,
+		Bare: &models.BareMetalPlugin{
+			Location: "/tmp/plugins",
+		},
+	}
+}
+
+func containerPlugin(name string) *models.Plugin {
+	return &models.Plugin{
+		Name: name,
+		Type: models.Container,
+		Container: &models.ContainerPlugin{
+			Image: "skarlso/container:v0.0.1",
+		},
+	}
+}
+
+func names(plugins []*models.Plugin) []string {
+	result := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+func testCreateAndGet(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	p := barePlugin("bare")
+	p.Description = "Says hello."
+	p.Owner = "team-infra"
+	p.Checksum = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	require.NoError(t, s.Create(ctx, p))
+	require.NoError(t, s.Create(ctx, containerPlugin("container")))
+
+	bare, err := s.Get(ctx, "bare")
+	require.NoError(t, err)
+	assert.True(t, bare.ID > 0)
+	assert.Equal(t, "bare", bare.Name)
+	assert.Equal(t, models.Bare, bare.Type)
+	require.NotNil(t, bare.Bare)
+	assert.Equal(t, "/tmp/plugins", bare.Bare.Location)
+	assert.Nil(t, bare.Container)
+	assert.Equal(t, "Says hello.", bare.Description)
+	assert.Equal(t, "team-infra", bare.Owner)
+	assert.Equal(t, "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", bare.Checksum)
+
+	container, err := s.Get(ctx, "container")
+	require.NoError(t, err)
+	assert.True(t, container.ID > 0)
+	assert.NotEqual(t, bare.ID, container.ID)
+	assert.Equal(t, models.Container, container.Type)
+	require.NotNil(t, container.Container)
+	assert.Equal(t, "skarlso/container:v0.0.1", container.Container.Image)
+	assert.Nil(t, container.Bare)
+}
+
+func testDuplicateNames(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	err := s.Create(ctx, containerPlugin("plugin"))
+	assert.ErrorIs(t, err, providers.ErrAlreadyExists)
+
+	// the original is left untouched
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, models.Bare, p.Type)
+}
+
+func testGetNotFound(t *testing.T, s providers.Storer) {
+	_, err := s.Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func testUpdate(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	before, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+
+	update := containerPlugin("plugin")
+	update.Description = "Now in a container."
+	update.Owner = "team-web"
+	update.Checksum = "sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+	require.NoError(t, s.Update(ctx, update))
+	after, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "Now in a container.", after.Description)
+	assert.Equal(t, "team-web", after.Owner)
+	assert.Equal(t, "sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9", after.Checksum)
+	assert.Equal(t, before.ID, after.ID)
+	assert.Equal(t, models.Container, after.Type)
+	require.NotNil(t, after.Container)
+	assert.Equal(t, "skarlso/container:v0.0.1", after.Container.Image)
+	assert.Nil(t, after.Bare)
+}
+
+func testUpdateNotFound(t *testing.T, s providers.Storer) {
+	err := s.Update(context.Background(), barePlugin("missing"))
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func testDelete(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	_, err := s.Get(ctx, "plugin")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+
+	// deleting something which doesn't exist is not an error
+	assert.NoError(t, s.Delete(ctx, "missing"))
+
+	// the name can be reused
+	assert.NoError(t, s.Create(ctx, containerPlugin("plugin")))
+}
+
+func testList(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	for _, name := range []string{"charlie", "alpha", "bravo"} {
+		require.NoError(t, s.Create(ctx, barePlugin(name)))
+	}
+	require.NoError(t, s.Delete(ctx, "alpha"))
+	require.NoError(t, s.Create(ctx, containerPlugin("delta")))
+
+	// plugins are listed in the order they were created
+	plugins, err = s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"charlie", "bravo", "delta"}, names(plugins))
+	require.NotNil(t, plugins[2].Container)
+	assert.Equal(t, "skarlso/container:v0.0.1", plugins[2].Container.Image)
+}
+
+func testListFilters(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("bare-1")))
+	require.NoError(t, s.Create(ctx, containerPlugin("container-1")))
+	require.NoError(t, s.Create(ctx, barePlugin("bare-2")))
+
+	plugins, err := s.List(ctx, providers.ListOpts{TypeFilter: models.Bare})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bare-1", "bare-2"}, names(plugins))
+
+	plugins, err = s.List(ctx, providers.ListOpts{TypeFilter: models.Container})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"container-1"}, names(plugins))
+
+	plugins, err = s.List(ctx, providers.ListOpts{TypeFilter: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+}
+
+func testListNameFilter(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	for _, name := range []string{"echo", "Echo-Server", "server", "100%_done", "1000-done"} {
+		require.NoError(t, s.Create(ctx, barePlugin(name)))
+	}
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		// substrings, ignoring case
+		{filter: "echo", want: []string{"echo", "Echo-Server"}},
+		{filter: "SERVER", want: []string{"Echo-Server", "server"}},
+		// globs have to match the whole name
+		{filter: "echo*", want: []string{"echo", "Echo-Server"}},
+		{filter: "*server", want: []string{"Echo-Server", "server"}},
+		{filter: "ech?", want: []string{"echo"}},
+		{filter: "serv", want: []string{"Echo-Server", "server"}},
+		{filter: "serv*r?", want: []string{}},
+		// like wildcards are taken literally
+		{filter: "%_done", want: []string{"100%_done"}},
+		{filter: "1*%*", want: []string{"100%_done"}},
+	}
+	for _, tt := range tests {
+		plugins, err := s.List(ctx, providers.ListOpts{NameFilter: tt.filter})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, names(plugins), "filter %q", tt.filter)
+	}
+}
+
+func testListSelector(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	plugins := map[string]map[string]string{
+		"infra-prod": {"team": "infra", "tier": "prod"},
+		"infra-dev":  {"team": "infra", "tier": "dev", "beta": ""},
+		"web":        {"team": "web"},
+		"unlabelled": nil,
+	}
+	for _, name := range []string{"infra-prod", "infra-dev", "web", "unlabelled"} {
+		p := barePlugin(name)
+		p.Labels = plugins[name]
+		require.NoError(t, s.Create(ctx, p))
+	}
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "team=infra", want: []string{"infra-prod", "infra-dev"}},
+		{selector: "team==web", want: []string{"web"}},
+		{selector: "team!=infra", want: []string{"web", "unlabelled"}},
+		{selector: "team", want: []string{"infra-prod", "infra-dev", "web"}},
+		{selector: "!team", want: []string{"unlabelled"}},
+		{selector: "beta", want: []string{"infra-dev"}},
+		{selector: "team=infra,tier!=dev", want: []string{"infra-prod"}},
+		{selector: "team=infra,!beta,tier=prod", want: []string{"infra-prod"}},
+		{selector: "team=nobody", want: []string{}},
+	}
+	for _, tt := range tests {
+		selector, err := providers.ParseSelector(tt.selector)
+		require.NoError(t, err)
+		result, err := s.List(ctx, providers.ListOpts{Selector: selector})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, names(result), "selector %q", tt.selector)
+	}
+}
+
+func testListSorting(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	base := time.Date(2021, 12, 21, 10, 0, 0, 0, time.UTC)
+	for i, name := range []string{"charlie", "Bravo", "alpha", "delta"} {
+		p := barePlugin(name)
+		// created in reverse order of the IDs
+		p.CreatedAt = base.Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, s.Create(ctx, p))
+	}
+	require.NoError(t, s.RecordRun(ctx, "alpha", base.Add(2*time.Hour)))
+	require.NoError(t, s.RecordRun(ctx, "charlie", base.Add(time.Hour)))
+
+	tests := []struct {
+		sortBy     providers.SortField
+		descending bool
+		want       []string
+	}{
+		{sortBy: providers.SortByID, want: []string{"charlie", "Bravo", "alpha", "delta"}},
+		{sortBy: providers.SortByID, descending: true, want: []string{"delta", "alpha", "Bravo", "charlie"}},
+		// byte order, upper case first
+		{sortBy: providers.SortByName, want: []string{"Bravo", "alpha", "charlie", "delta"}},
+		{sortBy: providers.SortByName, descending: true, want: []string{"delta", "charlie", "alpha", "Bravo"}},
+		{sortBy: providers.SortByCreated, want: []string{"delta", "alpha", "Bravo", "charlie"}},
+		{sortBy: providers.SortByCreated, descending: true, want: []string{"charlie", "Bravo", "alpha", "delta"}},
+		// plugins which never ran come first, in creation order
+		{sortBy: providers.SortByLastRun, want: []string{"Bravo", "delta", "charlie", "alpha"}},
+		{sortBy: providers.SortByLastRun, descending: true, want: []string{"alpha", "charlie", "delta", "Bravo"}},
+	}
+	for _, tt := range tests {
+		plugins, err := s.List(ctx, providers.ListOpts{SortBy: tt.sortBy, Descending: tt.descending})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, names(plugins), "sort %q descending %v", tt.sortBy, tt.descending)
+	}
+}
+
+func testListPagination(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, s.Create(ctx, barePlugin(name)))
+	}
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{limit: 2, want: []string{"a", "b"}},
+		{limit: 2, offset: 2, want: []string{"c", "d"}},
+		{limit: 2, offset: 4, want: []string{"e"}},
+		{limit: 2, offset: 5, want: []string{}},
+		{offset: 3, want: []string{"d", "e"}},
+		{limit: 10, want: []string{"a", "b", "c", "d", "e"}},
+		// negative values are treated like zero
+		{limit: -1, offset: -1, want: []string{"a", "b", "c", "d", "e"}},
+		{limit: 2, offset: -3, want: []string{"a", "b"}},
+		{limit: -2, offset: 3, want: []string{"d", "e"}},
+	}
+	for _, tt := range tests {
+		plugins, err := s.List(ctx, providers.ListOpts{Limit: tt.limit, Offset: tt.offset})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, names(plugins), "limit %d offset %d", tt.limit, tt.offset)
+	}
+	// pagination applies after filtering and sorting
+	plugins, err := s.List(ctx, providers.ListOpts{NameFilter: "?", SortBy: providers.SortByName, Descending: true, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "c"}, names(plugins))
+}
+
+func testLabels(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	p := barePlugin("plugin")
+	p.Labels = map[string]string{"team": "infra", "tier": "prod"}
+	require.NoError(t, s.Create(ctx, p))
+
+	stored, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "infra", "tier": "prod"}, stored.Labels)
+
+	// updates replace all labels
+	p.Labels = map[string]string{"team": "web"}
+	require.NoError(t, s.Update(ctx, p))
+	stored, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "web"}, stored.Labels)
+
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, map[string]string{"team": "web"}, plugins[0].Labels)
+
+	// labels of deleted plugins don't show up on a new plugin with the same name
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	stored, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Empty(t, stored.Labels)
+}
+
+func testRecordRun(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	before := time.Now()
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	stored, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.WithinDuration(t, before, stored.CreatedAt, time.Minute)
+	assert.True(t, stored.LastRun.IsZero())
+	createdAt := stored.CreatedAt
+
+	ranAt := time.Date(2021, 12, 21, 17, 52, 0, 0, time.UTC)
+	require.NoError(t, s.RecordRun(ctx, "plugin", ranAt))
+	stored, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.True(t, ranAt.Equal(stored.LastRun))
+
+	// updating the plugin keeps its timestamps
+	require.NoError(t, s.Update(ctx, containerPlugin("plugin")))
+	stored, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(stored.CreatedAt))
+	assert.True(t, ranAt.Equal(stored.LastRun))
+
+	err = s.RecordRun(ctx, "missing", ranAt)
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+}
+
+func versionedPlugin(name, version string) *models.Plugin {
+	p := barePlugin(name)
+	p.Version = version
+	p.Bare.Location = "/tmp/plugins/" + version
+	return p
+}
+
+func versions(plugins []*models.Plugin) []string {
+	result := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		result = append(result, p.Version)
+	}
+	return result
+}
+
+func testVersions(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	for _, v := range []string{"1.10.0", "1.2.0", "1.2.0-rc.1"} {
+		require.NoError(t, s.Create(ctx, versionedPlugin("plugin", v)))
+	}
+	require.NoError(t, s.Create(ctx, barePlugin("other")))
+	err := s.Create(ctx, versionedPlugin("plugin", "1.2.0"))
+	assert.ErrorIs(t, err, providers.ErrAlreadyExists)
+
+	// the first version is the active one
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", p.Version)
+	assert.True(t, p.Active)
+
+	p, err = s.Get(ctx, "plugin@1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", p.Version)
+	assert.False(t, p.Active)
+	assert.Equal(t, "/tmp/plugins/1.2.0", p.Bare.Location)
+
+	_, err = s.Get(ctx, "plugin@2.0.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	_, err = s.Get(ctx, "other@1.0.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+
+	// updates only touch the given version
+	update := versionedPlugin("plugin", "1.2.0")
+	update.Description = "The second one."
+	require.NoError(t, s.Update(ctx, update))
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Empty(t, p.Description)
+	assert.True(t, p.Active)
+	p, err = s.Get(ctx, "plugin@1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, "The second one.", p.Description)
+	assert.False(t, p.Active)
+
+	// versions are sorted by precedence
+	plugins, err := s.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other", "plugin", "plugin", "plugin"}, names(plugins))
+	assert.Equal(t, []string{"", "1.2.0-rc.1", "1.2.0", "1.10.0"}, versions(plugins))
+	plugins, err = s.List(ctx, providers.ListOpts{SortBy: providers.SortByName, Descending: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.10.0", "1.2.0", "1.2.0-rc.1", ""}, versions(plugins))
+
+	plugins, err = s.List(ctx, providers.ListOpts{SortBy: providers.SortByName, ActiveOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other", "plugin"}, names(plugins))
+	assert.Equal(t, []string{"", "1.10.0"}, versions(plugins))
+
+	// runs are recorded on the referenced version
+	at := time.Date(2021, 12, 21, 17, 52, 0, 0, time.UTC)
+	require.NoError(t, s.RecordRun(ctx, "plugin@1.2.0-rc.1", at))
+	p, err = s.Get(ctx, "plugin@1.2.0-rc.1")
+	require.NoError(t, err)
+	assert.True(t, at.Equal(p.LastRun))
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.True(t, p.LastRun.IsZero())
+}
+
+func testActivate(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, versionedPlugin("plugin", "1.1.0")))
+	require.NoError(t, s.Create(ctx, versionedPlugin("plugin", "1.2.0")))
+
+	require.NoError(t, s.Activate(ctx, "plugin", "1.2.0"))
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", p.Version)
+	plugins, err := s.List(ctx, providers.ListOpts{ActiveOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0"}, versions(plugins))
+
+	// activating the active version again changes nothing
+	require.NoError(t, s.Activate(ctx, "plugin", "1.2.0"))
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", p.Version)
+
+	err = s.Activate(ctx, "plugin", "2.0.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	err = s.Activate(ctx, "missing", "1.0.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", p.Version)
+}
+
+func testDeleteVersions(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	for _, v := range []string{"1.1.0", "1.3.0", "1.2.0"} {
+		require.NoError(t, s.Create(ctx, versionedPlugin("plugin", v)))
+	}
+	require.NoError(t, s.Create(ctx, versionedPlugin("other", "1.0.0")))
+
+	// removing an inactive version leaves the active one alone
+	require.NoError(t, s.Delete(ctx, "plugin@1.3.0"))
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", p.Version)
+
+	// removing the active version activates the latest one left
+	require.NoError(t, s.Create(ctx, versionedPlugin("plugin", "1.3.0")))
+	require.NoError(t, s.Delete(ctx, "plugin@1.1.0"))
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0", p.Version)
+	_, err = s.Get(ctx, "plugin@1.1.0")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+
+	// a plain name removes every version
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other"}, names(plugins))
+}
+
+func testConcurrentAccess(t *testing.T, s providers.Storer) {
+	ctx := context.Background()
+	const workers = 10
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		created int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("plugin-%d", i)
+			assert.NoError(t, s.Create(ctx, barePlugin(name)))
+			_, err := s.Get(ctx, name)
+			assert.NoError(t, err)
+			_, err = s.List(ctx, providers.ListOpts{})
+			assert.NoError(t, err)
+			// everyone races for the same name, only one of them may win
+			if err := s.Create(ctx, containerPlugin("contested")); err == nil {
+				lock.Lock()
+				created++
+				lock.Unlock()
+			} else {
+				assert.ErrorIs(t, err, providers.ErrAlreadyExists)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Len(t, plugins, workers+1)
+	ids := make(map[int]struct{})
+	for _, p := range plugins {
+		ids[p.ID] = struct{}{}
+	}
+	assert.Len(t, ids, workers+1, "every plugin has to get its own ID")
+}
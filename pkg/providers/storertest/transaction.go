@@ -0,0 +1,122 @@
+package storertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// RunTransaction runs the conformance suite for storers which implement providers.Transactional.
+func RunTransaction(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s providers.Storer, tx providers.Transactional)
+	}{
+		{name: "commit", test: testTransactionCommit},
+		{name: "rollback", test: testTransactionRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := factory(t)
+			tx, ok := s.(providers.Transactional)
+			require.True(t, ok, "storer doesn't implement providers.Transactional")
+			tt.test(t, s, tx)
+		})
+	}
+}
+
+// state describes every stored plugin, whether it's active and its description.
+func state(t *testing.T, s providers.Storer) []string {
+	plugins, err := s.List(context.Background(), providers.ListOpts{SortBy: providers.SortByName})
+	require.NoError(t, err)
+	result := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		r := providers.Ref(p.Name, p.Version)
+		if p.Active {
+			r += "*"
+		}
+		if p.Description != "" {
+			r += " " + p.Description
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// change creates c, updates plugin@1.0.0, activates plugin@2.0.0 and removes b, checking it can read what it changed.
+func change(ctx context.Context, t *testing.T, s providers.Storer) error {
+	if err := s.Create(ctx, barePlugin("c")); err != nil {
+		return err
+	}
+	updated := versionedPlugin("plugin", "1.0.0")
+	updated.Description = "updated"
+	if err := s.Update(ctx, updated); err != nil {
+		return err
+	}
+	if err := s.Activate(ctx, "plugin", "2.0.0"); err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, "b"); err != nil {
+		return err
+	}
+	assert.Equal(t, []string{"c@*", "plugin@1.0.0 updated", "plugin@2.0.0*"}, state(t, s))
+	return nil
+}
+
+func createTransactionPlugins(ctx context.Context, t *testing.T, s providers.Storer) {
+	for _, p := range []*models.Plugin{versionedPlugin("plugin", "1.0.0"), versionedPlugin("plugin", "2.0.0"), barePlugin("b")} {
+		require.NoError(t, s.Create(ctx, p))
+	}
+}
+
+func testTransactionCommit(t *testing.T, s providers.Storer, tx providers.Transactional) {
+	ctx := context.Background()
+	createTransactionPlugins(ctx, t, s)
+
+	require.NoError(t, tx.Transaction(ctx, func(s providers.Storer) error {
+		return change(ctx, t, s)
+	}))
+	assert.Equal(t, []string{"c@*", "plugin@1.0.0 updated", "plugin@2.0.0*"}, state(t, s))
+	if trash, ok := s.(providers.Trasher); ok {
+		assert.Equal(t, []string{"b@"}, trashed(t, trash))
+	}
+}
+
+func testTransactionRollback(t *testing.T, s providers.Storer, tx providers.Transactional) {
+	ctx := context.Background()
+	createTransactionPlugins(ctx, t, s)
+	before := state(t, s)
+	var events int
+	auditor, audits := s.(providers.Auditor)
+	if audits {
+		log, err := auditor.AuditLog(ctx, providers.AuditOpts{})
+		require.NoError(t, err)
+		events = len(log)
+	}
+
+	boom := errors.New("boom")
+	err := tx.Transaction(ctx, func(s providers.Storer) error {
+		if err := change(ctx, t, s); err != nil {
+			return err
+		}
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	// nothing is left behind, not even in the trash or the audit log
+	assert.Equal(t, before, state(t, s))
+	if trash, ok := s.(providers.Trasher); ok {
+		assert.Empty(t, trashed(t, trash))
+	}
+	if audits {
+		log, err := auditor.AuditLog(ctx, providers.AuditOpts{})
+		require.NoError(t, err)
+		assert.Len(t, log, events)
+	}
+}
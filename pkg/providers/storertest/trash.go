@@ -0,0 +1,183 @@
+package storertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+)
+
+// expiringRetention is the trash retention of the test cases about expired plugins.
+const expiringRetention = 200 * time.Millisecond
+
+// TrashFactory returns a new, empty and initialised Storer which keeps removed plugins for the retention.
+type TrashFactory func(t *testing.T, retention time.Duration) providers.Storer
+
+// RunTrash runs the conformance suite for storers which implement providers.Trasher.
+func RunTrash(t *testing.T, factory TrashFactory) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		test      func(t *testing.T, s providers.Storer, trash providers.Trasher)
+	}{
+		{name: "delete moves to trash", test: testTrashDelete},
+		{name: "restore", test: testTrashRestore},
+		{name: "restore versions", test: testTrashRestoreVersions},
+		{name: "restore conflicts", test: testTrashRestoreConflicts},
+		{name: "purge", test: testTrashPurge},
+		{name: "expired", retention: expiringRetention, test: testTrashExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retention := tt.retention
+			if retention == 0 {
+				retention = providers.DefaultTrashRetention
+			}
+			s := factory(t, retention)
+			trash, ok := s.(providers.Trasher)
+			require.True(t, ok, "storer doesn't implement providers.Trasher")
+			tt.test(t, s, trash)
+		})
+	}
+}
+
+func trashed(t *testing.T, trash providers.Trasher) []string {
+	plugins, err := trash.ListTrash(context.Background())
+	require.NoError(t, err)
+	var result []string
+	for _, p := range plugins {
+		result = append(result, providers.Ref(p.Plugin.Name, p.Plugin.Version))
+	}
+	return result
+}
+
+func testTrashDelete(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	p := barePlugin("plugin")
+	p.Labels = map[string]string{"team": "infra"}
+	require.NoError(t, s.Create(ctx, p))
+	require.NoError(t, s.Create(ctx, barePlugin("other")))
+	before := time.Now()
+	require.NoError(t, s.Delete(ctx, "plugin"))
+
+	_, err := s.Get(ctx, "plugin")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+	plugins, err := s.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other"}, names(plugins))
+
+	list, err := trash.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "plugin", list[0].Plugin.Name)
+	assert.Equal(t, "infra", list[0].Plugin.Labels["team"])
+	assert.WithinDuration(t, before, list[0].DeletedAt, 5*time.Second)
+	assert.True(t, list[0].ExpiresAt.After(list[0].DeletedAt))
+}
+
+func testTrashRestore(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	p := barePlugin("plugin")
+	p.Labels = map[string]string{"team": "infra"}
+	p.Description = "a plugin"
+	require.NoError(t, s.Create(ctx, p))
+	created, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, "plugin"))
+
+	require.NoError(t, trash.RestoreTrashed(ctx, "plugin"))
+	restored, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "a plugin", restored.Description)
+	assert.Equal(t, "infra", restored.Labels["team"])
+	assert.True(t, restored.Active)
+	assert.True(t, created.CreatedAt.Equal(restored.CreatedAt))
+	assert.Empty(t, trashed(t, trash))
+
+	assert.ErrorIs(t, trash.RestoreTrashed(ctx, "plugin"), providers.ErrNotFound)
+	assert.ErrorIs(t, trash.RestoreTrashed(ctx, "missing"), providers.ErrNotFound)
+}
+
+func testTrashRestoreVersions(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	for _, v := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		require.NoError(t, s.Create(ctx, versionedPlugin("plugin", v)))
+	}
+	require.NoError(t, s.Delete(ctx, "plugin@1.0.0"))
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	// most recently removed first
+	assert.Equal(t, []string{"plugin@3.0.0", "plugin@2.0.0", "plugin@1.0.0"}, trashed(t, trash))
+
+	require.NoError(t, trash.RestoreTrashed(ctx, "plugin@2.0.0"))
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", p.Version)
+
+	// a restored version doesn't replace the active one
+	require.NoError(t, trash.RestoreTrashed(ctx, "plugin"))
+	plugins, err := s.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "2.0.0", "3.0.0"}, versions(plugins))
+	p, err = s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", p.Version)
+}
+
+func testTrashRestoreConflicts(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("plugin")))
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	require.NoError(t, s.Create(ctx, containerPlugin("plugin")))
+
+	assert.ErrorIs(t, trash.RestoreTrashed(ctx, "plugin"), providers.ErrAlreadyExists)
+	p, err := s.Get(ctx, "plugin")
+	require.NoError(t, err)
+	assert.NotNil(t, p.Container)
+	assert.Equal(t, []string{"plugin@"}, trashed(t, trash))
+
+	// removing it again replaces the trashed one
+	require.NoError(t, s.Delete(ctx, "plugin"))
+	list, err := trash.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.NotNil(t, list[0].Plugin.Container)
+}
+
+func testTrashPurge(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("first")))
+	require.NoError(t, s.Delete(ctx, "first"))
+	n, err := trash.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	require.NoError(t, s.Create(ctx, barePlugin("second")))
+	require.NoError(t, s.Delete(ctx, "second"))
+	n, err = trash.PurgeTrash(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, trashed(t, trash))
+	assert.ErrorIs(t, trash.RestoreTrashed(ctx, "first"), providers.ErrNotFound)
+}
+
+func testTrashExpired(t *testing.T, s providers.Storer, trash providers.Trasher) {
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, barePlugin("old")))
+	require.NoError(t, s.Delete(ctx, "old"))
+	time.Sleep(2 * expiringRetention)
+
+	// expired plugins are neither listed nor restored, even before the next removal purges them
+	assert.Empty(t, trashed(t, trash))
+	assert.ErrorIs(t, trash.RestoreTrashed(ctx, "old"), providers.ErrNotFound)
+	_, err := s.Get(ctx, "old")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+
+	require.NoError(t, s.Create(ctx, barePlugin("new")))
+	require.NoError(t, s.Delete(ctx, "new"))
+	assert.Equal(t, []string{"new@"}, trashed(t, trash))
+	require.NoError(t, trash.RestoreTrashed(ctx, "new"))
+}
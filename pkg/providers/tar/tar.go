@@ -3,11 +3,15 @@ package tar
 import (
 	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/rs/zerolog"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
 )
 
 // Dependencies .
@@ -20,6 +24,8 @@ type Tarer struct {
 	Logger zerolog.Logger
 }
 
+var _ providers.Archiver = &Tarer{}
+
 // NewTarer creates a provider which can tar / untar archives.
 func NewTarer(logger zerolog.Logger) *Tarer {
 	return &Tarer{
@@ -60,6 +66,10 @@ func (t *Tarer) Untar(dst string, r io.Reader) error {
 
 		// the target location where the dir/file should be created
 		target := filepath.Join(dst, header.Name)
+		// archives are downloaded, so make sure none of the files end up outside of dst
+		if rel, err := filepath.Rel(dst, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q is outside of the destination", header.Name)
+		}
 
 		// the following switch could also be done using fi.Mode(), not sure if there is
 		// a benefit of using one vs. the other.
@@ -69,7 +79,10 @@ func (t *Tarer) Untar(dst string, r io.Reader) error {
 		if header.Typeflag != tar.TypeReg {
 			continue
 		}
-		f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(header.Mode))
 		if err != nil {
 			return err
 		}
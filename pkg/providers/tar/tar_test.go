@@ -1,6 +1,9 @@
 package tar
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -23,3 +26,23 @@ func TestUntar(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte("this is a test file\n"), content)
 }
+
+func TestUntarRejectsEntriesOutsideOfDestination(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	content := []byte("#!/bin/sh\n")
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escaped", Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	parent := t.TempDir()
+	dst := filepath.Join(parent, "dst")
+	assert.NoError(t, os.Mkdir(dst, 0755))
+	err = NewTarer(zerolog.New(os.Stderr)).Untar(dst, buf)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(parent, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
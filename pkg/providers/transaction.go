@@ -0,0 +1,11 @@
+package providers
+
+import "context"
+
+// Transactional is implemented by storers which can make several changes at once, so either all of them
+// are stored or none.
+type Transactional interface {
+	// Transaction runs fn with a Storer whose changes are committed once fn returns nil and rolled back,
+	// leaving nothing behind, if it returns an error.
+	Transaction(ctx context.Context, fn func(s Storer) error) error
+}
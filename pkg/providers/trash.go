@@ -0,0 +1,24 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// DefaultTrashRetention is how long removed plugins are kept in the trash unless configured otherwise.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// Trasher is implemented by storers which move removed plugins to a trash instead of deleting them,
+// so they can be restored until they expire.
+type Trasher interface {
+	// ListTrash returns the plugins in the trash which haven't expired yet, the most recently removed first.
+	ListTrash(ctx context.Context) ([]*models.TrashedPlugin, error)
+	// RestoreTrashed brings back a removed version of a plugin, or all removed versions of it given
+	// a plain name. It returns ErrNotFound if there is nothing to restore, expired plugins included, and
+	// ErrAlreadyExists if the version has been added again since.
+	RestoreTrashed(ctx context.Context, ref string) error
+	// PurgeTrash deletes the plugins removed before the given time for good and returns how many there were.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+// Plugins are referred to as `name` or `name@version`. A plain name refers to the active version of
+// a plugin when reading or running it and to all of its versions when deleting it. `name@` refers to
+// the version of the plugin which has no version.
+
+// ParseRef splits a plugin reference into its name and version. pinned is true if the reference
+// names a version, even if that version is empty.
+func ParseRef(ref string) (name, version string, pinned bool) {
+	i := strings.Index(ref, "@")
+	if i < 0 {
+		return ref, "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
+
+// Ref returns the reference of a single version of a plugin.
+func Ref(name, version string) string {
+	return name + "@" + version
+}
+
+// ParseVersion parses a semantic version, which may start with a v. Anything looser, like `1.2`, isn't one.
+func ParseVersion(version string) (*semver.Version, error) {
+	return semver.StrictNewVersion(strings.TrimPrefix(version, "v"))
+}
+
+// VersionKey returns a string which sorts like the version does. Semantic versions sort by precedence,
+// after any version which isn't one. Those are sorted alphabetically and the empty version comes first.
+// Storers save the key next to the version so databases can order by it.
+func VersionKey(version string) string {
+	if version == "" {
+		return ""
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "0" + version
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "1%020d.%020d.%020d", v.Major(), v.Minor(), v.Patch())
+	if v.Prerelease() == "" {
+		// a release has a higher precedence than any of its pre-releases
+		b.WriteString("~")
+		return b.String()
+	}
+	b.WriteString("-")
+	for i, id := range strings.Split(v.Prerelease(), ".") {
+		if i > 0 {
+			// lower than any character allowed in an identifier, so `alpha` comes before `alpha.1` and `alpha-1`
+			b.WriteString(" ")
+		}
+		if isNumeric(id) {
+			// numeric identifiers are compared numerically and have a lower precedence than alphanumeric ones
+			b.WriteString("0")
+			if len(id) < 20 {
+				b.WriteString(strings.Repeat("0", 20-len(id)))
+			}
+			b.WriteString(id)
+		} else {
+			b.WriteString("1" + id)
+		}
+	}
+	return b.String()
+}
+
+// Latest returns the highest version of the given plugins, which all have the same name. Of equal versions
+// the one created last wins. It returns nil if there are no plugins.
+func Latest(plugins []*models.Plugin) *models.Plugin {
+	var latest *models.Plugin
+	for _, p := range plugins {
+		if latest == nil || VersionKey(p.Version) > VersionKey(latest.Version) ||
+			VersionKey(p.Version) == VersionKey(latest.Version) && p.ID > latest.ID {
+			latest = p
+		}
+	}
+	return latest
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
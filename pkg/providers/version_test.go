@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRef(t *testing.T) {
+	name, version, pinned := ParseRef("echo")
+	assert.Equal(t, "echo", name)
+	assert.Equal(t, "", version)
+	assert.False(t, pinned)
+
+	name, version, pinned = ParseRef("echo@1.2.0")
+	assert.Equal(t, "echo", name)
+	assert.Equal(t, "1.2.0", version)
+	assert.True(t, pinned)
+
+	name, version, pinned = ParseRef(Ref("echo", ""))
+	assert.Equal(t, "echo", name)
+	assert.Equal(t, "", version)
+	assert.True(t, pinned)
+}
+
+func TestVersionKey(t *testing.T) {
+	ordered := []string{
+		"",
+		"latest",
+		"nightly",
+		"0.9.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"v1.0.0",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	shuffled := []string{"1.10.0", "latest", "1.0.0-beta.11", "2.0.0", "1.0.0-alpha.1", "", "1.0.0-rc.1", "0.9.0",
+		"1.0.0-alpha.beta", "nightly", "1.2.0", "1.0.0-beta", "v1.0.0", "1.0.0-alpha", "1.0.0-beta.2"}
+	sort.Slice(shuffled, func(i, j int) bool {
+		return VersionKey(shuffled[i]) < VersionKey(shuffled[j])
+	})
+	assert.Equal(t, ordered, shuffled)
+}
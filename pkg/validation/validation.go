@@ -0,0 +1,172 @@
+// Package validation checks plugins before they are stored. It returns every problem at once, so they
+// can all be fixed in one go, whether the plugin came from the command line or anywhere else.
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+var (
+	// names end up in file names and in references like name@version, so they are kept simple
+	namePattern     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+	versionPattern  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._+-]*$`)
+	checksumPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// maxNameLength keeps names usable as file names everywhere.
+const maxNameLength = 128
+
+// FieldError is a problem with a single field of a plugin. Field uses the json name of the field.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors are all the problems found with a plugin.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, f := range e {
+		messages = append(messages, f.Error())
+	}
+	return "invalid plugin: " + strings.Join(messages, "; ")
+}
+
+// Plugin checks the definition of a plugin and, for a bare plugin, that its binary exists and can be run.
+// It returns Errors if there are any problems.
+func Plugin(p *models.Plugin) error {
+	errs := definition(p)
+	if p.Type == models.Bare && p.Bare != nil && p.Bare.Location != "" && namePattern.MatchString(p.Name) {
+		errs = append(errs, binary(filepath.Join(p.Bare.Location, p.Name))...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Definition checks the fields of a plugin without looking at the file system, for plugins whose binary
+// doesn't have to be present, like ones which are already stored. It returns Errors if there are any problems.
+func Definition(p *models.Plugin) error {
+	if errs := definition(p); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Name checks the name of a plugin on its own, for names which end up in paths before there is a whole
+// plugin to check, like the ones in a catalog. It returns a *FieldError.
+func Name(name string) error {
+	if err := nameError(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Version checks a version which is set, like Name does for names. It returns a *FieldError.
+func Version(version string) error {
+	if err := versionError(version); err != nil {
+		return err
+	}
+	return nil
+}
+
+func nameError(name string) *FieldError {
+	switch {
+	case name == "":
+		return &FieldError{Field: "name", Message: "is required"}
+	case len(name) > maxNameLength:
+		return &FieldError{Field: "name", Message: fmt.Sprintf("can't be longer than %d characters", maxNameLength)}
+	case !namePattern.MatchString(name):
+		return &FieldError{Field: "name", Message: fmt.Sprintf("%q has to start with a letter or digit and contain only letters, digits, '.', '_' and '-'", name)}
+	}
+	return nil
+}
+
+func versionError(version string) *FieldError {
+	if !versionPattern.MatchString(version) {
+		return &FieldError{Field: "version", Message: fmt.Sprintf("%q has to start with a letter or digit and contain only letters, digits, '.', '_', '+' and '-'", version)}
+	}
+	return nil
+}
+
+func definition(p *models.Plugin) Errors {
+	var errs Errors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if err := nameError(p.Name); err != nil {
+		errs = append(errs, err)
+	}
+	if p.Version != "" {
+		if err := versionError(p.Version); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch p.Type {
+	case models.Container:
+		switch {
+		case p.Container == nil || p.Container.Image == "":
+			add("container.image", "is required for container plugins")
+		default:
+			if _, err := reference.ParseNormalizedNamed(p.Container.Image); err != nil {
+				add("container.image", "%q is not a valid image reference: %s", p.Container.Image, err)
+			}
+		}
+		if p.Bare != nil {
+			add("bare", "can't be set for container plugins")
+		}
+	case models.Bare:
+		if p.Bare == nil || p.Bare.Location == "" {
+			add("bare.location", "is required for bare plugins")
+		}
+		if p.Container != nil {
+			add("container", "can't be set for bare plugins")
+		}
+	case "":
+		add("type", "is required")
+	default:
+		add("type", "%q has to be %s or %s", p.Type, models.Bare, models.Container)
+	}
+	if p.Checksum != "" && !checksumPattern.MatchString(p.Checksum) {
+		add("checksum", "%q has to be sha256: followed by 64 lower case hex digits", p.Checksum)
+	}
+	for k, v := range p.Labels {
+		if k == "" || strings.ContainsAny(k, "!=, \t") {
+			add("labels", "invalid key %q", k)
+		}
+		if strings.Contains(v, ",") {
+			add("labels", "value of %q can't contain a comma", k)
+		}
+	}
+	return errs
+}
+
+// binary checks that the file is an executable regular file.
+func binary(path string) Errors {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return Errors{{Field: "bare.location", Message: fmt.Sprintf("there is no binary at %s", path)}}
+	case err != nil:
+		return Errors{{Field: "bare.location", Message: fmt.Sprintf("can't read %s: %s", path, err)}}
+	case !info.Mode().IsRegular():
+		return Errors{{Field: "bare.location", Message: fmt.Sprintf("%s is not a regular file", path)}}
+	case info.Mode().Perm()&0o111 == 0:
+		return Errors{{Field: "bare.location", Message: fmt.Sprintf("%s is not executable", path)}}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+)
+
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs Errors
+	require.True(t, errors.As(err, &errs), "unexpected error %v", err)
+	var result []string
+	for _, e := range errs {
+		result = append(result, e.Field)
+	}
+	return result
+}
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *models.Plugin
+		fields []string
+	}{
+		{
+			name:   "valid container",
+			plugin: &models.Plugin{Name: "echo", Version: "1.2.0-rc.1+build", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:echo-v1"}},
+		},
+		{
+			name:   "valid image with registry and digest",
+			plugin: &models.Plugin{Name: "echo", Type: models.Container, Container: &models.ContainerPlugin{Image: "ghcr.io/skarlso/echo@sha256:" + sha}},
+		},
+		{
+			name:   "valid bare",
+			plugin: &models.Plugin{Name: "echo_2.x", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/nowhere"}, Checksum: "sha256:" + sha, Labels: map[string]string{"team": "infra"}},
+		},
+		{
+			name:   "everything wrong at once",
+			plugin: &models.Plugin{Name: "", Version: "../1", Type: models.Container, Checksum: "md5:abc", Labels: map[string]string{"a,b": "c"}},
+			fields: []string{"name", "version", "container.image", "checksum", "labels"},
+		},
+		{
+			name:   "bad names",
+			plugin: &models.Plugin{Name: "a@b", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/"}},
+			fields: []string{"name"},
+		},
+		{
+			name:   "hidden name",
+			plugin: &models.Plugin{Name: ".echo", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/"}},
+			fields: []string{"name"},
+		},
+		{
+			name:   "invalid image",
+			plugin: &models.Plugin{Name: "echo", Type: models.Container, Container: &models.ContainerPlugin{Image: "Skarlso/Providers:echo v1"}},
+			fields: []string{"container.image"},
+		},
+		{
+			name:   "bare without location",
+			plugin: &models.Plugin{Name: "echo", Type: models.Bare, Container: &models.ContainerPlugin{Image: "echo"}},
+			fields: []string{"bare.location", "container"},
+		},
+		{
+			name:   "unknown type",
+			plugin: &models.Plugin{Name: "echo", Type: "vm"},
+			fields: []string{"type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.fields, fields(t, Definition(tt.plugin)))
+		})
+	}
+}
+
+const sha = "dc09554d11862dd2d3800b6f65352f89b2639f9ec877ef35697d8b959f17c9dd"
+
+func TestPluginChecksBinary(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "echo"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), []byte("data"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "folder"), 0o755))
+	bare := func(name string) *models.Plugin {
+		return &models.Plugin{Name: name, Type: models.Bare, Bare: &models.BareMetalPlugin{Location: dir}}
+	}
+
+	assert.NoError(t, Plugin(bare("echo")))
+	for _, name := range []string{"missing", "data", "folder"} {
+		err := Plugin(bare(name))
+		assert.Equal(t, []string{"bare.location"}, fields(t, err), name)
+	}
+	assert.EqualError(t, Plugin(bare("data")), "invalid plugin: bare.location: "+filepath.Join(dir, "data")+" is not executable")
+	// the definition alone doesn't care about the binary
+	assert.NoError(t, Definition(bare("missing")))
+}
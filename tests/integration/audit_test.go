@@ -0,0 +1,79 @@
+package livestore
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+func TestLiteStorer_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+	require.NoError(t, err)
+	start := time.Now()
+
+	bob := &models.Plugin{Name: "bob", Version: "1.0.0", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:echo-v1"}}
+	require.NoError(t, l.Create(ctx, bob))
+	bob2 := *bob
+	bob2.Version = "2.0.0"
+	require.NoError(t, l.Create(ctx, &bob2))
+	require.NoError(t, l.Create(ctx, &models.Plugin{Name: "alice", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/bin"}}))
+	replaced := *bob
+	replaced.Container = &models.ContainerPlugin{Image: "evil/echo"}
+	require.NoError(t, l.Update(ctx, &replaced))
+	// neither changes anything, so neither is recorded
+	require.NoError(t, l.Update(ctx, &replaced))
+	require.NoError(t, l.Activate(ctx, "bob", "1.0.0"))
+	require.NoError(t, l.RecordRun(ctx, "bob", time.Now()))
+	require.NoError(t, l.Activate(ctx, "bob", "2.0.0"))
+	require.NoError(t, l.Delete(ctx, "bob"))
+	// a failed change isn't recorded
+	assert.ErrorIs(t, l.Create(ctx, &models.Plugin{Name: "alice", Type: models.Bare}), providers.ErrAlreadyExists)
+
+	events, err := l.AuditLog(ctx, providers.AuditOpts{Ref: "bob"})
+	require.NoError(t, err)
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action+" "+providers.Ref(e.Name, e.Version))
+		assert.NotEmpty(t, e.User)
+		assert.False(t, e.At.Before(start.Add(-time.Second)))
+	}
+	assert.Equal(t, []string{
+		"create bob@1.0.0",
+		"create bob@2.0.0",
+		"update bob@1.0.0",
+		// the version which was active before is recorded too
+		"activate bob@1.0.0",
+		"activate bob@2.0.0",
+		"delete bob@1.0.0",
+		"delete bob@2.0.0",
+	}, actions)
+
+	update := events[2]
+	assert.Equal(t, "skarlso/providers:echo-v1", update.Before.Container.Image)
+	assert.Equal(t, "evil/echo", update.After.Container.Image)
+	assert.Nil(t, events[0].Before)
+	assert.True(t, events[3].Before.Active)
+	assert.False(t, events[3].After.Active)
+	assert.True(t, events[4].After.Active)
+	assert.Nil(t, events[5].After)
+
+	events, err = l.AuditLog(ctx, providers.AuditOpts{Ref: "bob@2.0.0"})
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+	events, err = l.AuditLog(ctx, providers.AuditOpts{})
+	require.NoError(t, err)
+	assert.Len(t, events, 8)
+	events, err = l.AuditLog(ctx, providers.AuditOpts{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,111 @@
+package livestore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+func TestLiteStorer_BackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	location := t.TempDir()
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	require.NoError(t, err)
+	require.NoError(t, l.Create(ctx, &models.Plugin{Name: "bob", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/bin"}}))
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, l.Backup(ctx, backup))
+	assert.ErrorIs(t, l.Backup(ctx, backup), providers.ErrAlreadyExists)
+
+	require.NoError(t, l.Delete(ctx, "bob"))
+	require.NoError(t, l.Create(ctx, &models.Plugin{Name: "alice", Type: models.Bare, Bare: &models.BareMetalPlugin{Location: "/bin"}}))
+	require.NoError(t, l.Restore(ctx, backup))
+	plugins, err := l.List(ctx, providers.ListOpts{})
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "bob", plugins[0].Name)
+
+	// a restored backup can be used by a new storer
+	_, err = storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	assert.NoError(t, err)
+}
+
+func TestLiteStorer_RestoreChecksSchema(t *testing.T) {
+	ctx := context.Background()
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+	require.NoError(t, err)
+
+	// a database which isn't a plugin database
+	other := filepath.Join(t.TempDir(), "other.db")
+	db, err := sql.Open("sqlite3", other)
+	require.NoError(t, err)
+	_, err = db.Exec(`create table things (id integer primary key);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.EqualError(t, l.Restore(ctx, other), other+" is not a plugin database")
+
+	// a backup made by a newer version
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, l.Backup(ctx, backup))
+	db, err = sql.Open("sqlite3", backup)
+	require.NoError(t, err)
+	_, err = db.Exec(`insert into schema_migrations(version) values(1000);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	err = l.Restore(ctx, backup)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than the supported version")
+}
+
+func TestLiteStorer_BacksUpBeforeMigrating(t *testing.T) {
+	location := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(location, "provider.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`create table plugins (id integer primary key, name text unique, type text, location text, image text);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	require.NoError(t, err)
+	backups, err := filepath.Glob(filepath.Join(location, "backups", "provider-0-*.db"))
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+
+	// nothing to migrate, nothing to back up
+	_, err = storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	require.NoError(t, err)
+	backups, err = filepath.Glob(filepath.Join(location, "backups", "*"))
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestLiteStorer_TrashRetention(t *testing.T) {
+	ctx := context.Background()
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, providers.DefaultTrashRetention, l.TrashRetention)
+
+	require.NoError(t, l.Create(ctx, &models.Plugin{Name: "old", Type: models.Bare}))
+	require.NoError(t, l.Delete(ctx, "old"))
+	// removing something drops whatever has been in the trash for longer than the retention
+	l.TrashRetention = 0
+	require.NoError(t, l.Create(ctx, &models.Plugin{Name: "new", Type: models.Bare}))
+	require.NoError(t, l.Delete(ctx, "new"))
+	// with the default retention, anything which wasn't dropped would be listed
+	l.TrashRetention = providers.DefaultTrashRetention
+	trash, err := l.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, trash, 1)
+	assert.Equal(t, "new", trash[0].Plugin.Name)
+	assert.Equal(t, trash[0].DeletedAt.Add(providers.DefaultTrashRetention), trash[0].ExpiresAt)
+}
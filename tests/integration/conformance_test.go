@@ -0,0 +1,47 @@
+package livestore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+	"github.com/Skarlso/providers-example/pkg/providers/storertest"
+)
+
+func TestLiteStorer_Conformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) providers.Storer {
+		l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		return l
+	})
+}
+
+func TestLiteStorer_TrashConformance(t *testing.T) {
+	storertest.RunTrash(t, func(t *testing.T, retention time.Duration) providers.Storer {
+		l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		l.TrashRetention = retention
+		return l
+	})
+}
+
+func TestLiteStorer_TransactionConformance(t *testing.T) {
+	storertest.RunTransaction(t, func(t *testing.T) providers.Storer {
+		l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		return l
+	})
+}
+
+func TestLiteStorer_PipelineConformance(t *testing.T) {
+	storertest.RunPipelines(t, func(t *testing.T) providers.Storer {
+		l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		return l
+	})
+}
@@ -0,0 +1,152 @@
+package livestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+	"github.com/Skarlso/providers-example/pkg/providers/storertest"
+)
+
+func TestFileStorer_Conformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) providers.Storer {
+		f, err := storer.NewFileStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		return f
+	})
+}
+
+func TestFileStorer_TrashConformance(t *testing.T) {
+	storertest.RunTrash(t, func(t *testing.T, retention time.Duration) providers.Storer {
+		f, err := storer.NewFileStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		f.TrashRetention = retention
+		return f
+	})
+}
+
+func TestFileStorer_TransactionConformance(t *testing.T) {
+	storertest.RunTransaction(t, func(t *testing.T) providers.Storer {
+		f, err := storer.NewFileStorer(zerolog.New(os.Stderr), t.TempDir())
+		require.NoError(t, err)
+		return f
+	})
+}
+
+func TestFileStorer_Documents(t *testing.T) {
+	dir := t.TempDir()
+	f, err := storer.NewFileStorer(zerolog.New(os.Stderr), dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+	err = f.Create(ctx, &models.Plugin{
+		Name: "echo",
+		Type: models.Container,
+		Container: &models.ContainerPlugin{
+			Image: "skarlso/providers:echo-v1",
+		},
+		Labels:    map[string]string{"team": "infra"},
+		CreatedAt: time.Date(2021, 12, 21, 17, 52, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "echo.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, `id: 1
+name: echo
+active: true
+type: container
+image: skarlso/providers:echo-v1
+labels:
+    team: infra
+createdAt: 2021-12-21T17:52:00Z
+`, string(content))
+	// no temporary files or locks are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// names can't escape the directory
+	err = f.Create(ctx, &models.Plugin{Name: "../escape", Type: models.Bare})
+	assert.Error(t, err)
+}
+
+func TestFileStorer_Lock(t *testing.T) {
+	dir := t.TempDir()
+	f, err := storer.NewFileStorer(zerolog.New(os.Stderr), dir)
+	require.NoError(t, err)
+	f.LockTimeout = 50 * time.Millisecond
+	// somebody else is writing
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".lock"), []byte("1"), 0644))
+
+	err = f.Create(context.Background(), &models.Plugin{Name: "echo", Type: models.Bare})
+	assert.Error(t, err)
+	_, err = f.Get(context.Background(), "echo")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
+
+	// a lock left behind by a crashed process is removed
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, ".lock"), old, old))
+	assert.NoError(t, f.Create(context.Background(), &models.Plugin{Name: "echo", Type: models.Bare}))
+}
+
+func TestFileStorer_StaleLockRace(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, ".lock")
+	require.NoError(t, os.WriteFile(lock, []byte("1"), 0644))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(lock, old, old))
+
+	// every writer finds the stale lock, but only one of them may hold the lock at a time
+	const writers = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		f, err := storer.NewFileStorer(zerolog.New(io.Discard), dir)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func(i int, f *storer.FileStorer) {
+			defer wg.Done()
+			<-start
+			errs <- f.Create(context.Background(), &models.Plugin{Name: "echo", Version: fmt.Sprintf("1.0.%d", i), Type: models.Bare})
+		}(i, f)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	f, err := storer.NewFileStorer(zerolog.New(os.Stderr), dir)
+	require.NoError(t, err)
+	plugins, err := f.List(context.Background(), providers.ListOpts{})
+	require.NoError(t, err)
+	require.Len(t, plugins, writers)
+	ids := make(map[int]bool)
+	active := 0
+	for _, p := range plugins {
+		ids[p.ID] = true
+		if p.Active {
+			active++
+		}
+	}
+	assert.Len(t, ids, writers, "two writers held the lock at once")
+	assert.Equal(t, 1, active)
+	_, err = os.Stat(lock)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(lock + ".break")
+	assert.True(t, os.IsNotExist(err))
+}
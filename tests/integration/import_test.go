@@ -0,0 +1,80 @@
+package livestore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/models"
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/registry"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+// failingActivation fails every activation made in a transaction, after everything else was changed.
+type failingActivation struct {
+	*storer.LiteStorer
+}
+
+func (f *failingActivation) Transaction(ctx context.Context, fn func(s providers.Storer) error) error {
+	return f.LiteStorer.Transaction(ctx, func(s providers.Storer) error {
+		return fn(&noActivation{Storer: s})
+	})
+}
+
+type noActivation struct {
+	providers.Storer
+}
+
+func (n *noActivation) Activate(ctx context.Context, name, version string) error {
+	return errors.New("boom")
+}
+
+func TestLiteStorer_FailedImportLeavesNothingBehind(t *testing.T) {
+	ctx := context.Background()
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), t.TempDir())
+	require.NoError(t, err)
+	bob := &models.Plugin{Name: "bob", Version: "1.0.0", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:echo-v1"}}
+	bob2 := &models.Plugin{Name: "bob", Version: "2.0.0", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:echo-v2"}}
+	carol := &models.Plugin{Name: "carol", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:carol"}}
+	for _, p := range []*models.Plugin{bob, bob2, carol} {
+		require.NoError(t, l.Create(ctx, p))
+	}
+	before, err := l.AuditLog(ctx, providers.AuditOpts{})
+	require.NoError(t, err)
+
+	// creates alice, updates bob@1.0.0, deletes carol and then fails to activate bob@2.0.0
+	updated := *bob
+	updated.Container = &models.ContainerPlugin{Image: "skarlso/providers:echo-v1.1"}
+	active := *bob2
+	active.Active = true
+	doc := &registry.Document{Plugins: []*models.Plugin{
+		{Name: "alice", Type: models.Container, Container: &models.ContainerPlugin{Image: "skarlso/providers:alice"}},
+		&updated,
+		&active,
+	}}
+	_, err = registry.NewImporter(zerolog.New(os.Stderr), &failingActivation{LiteStorer: l}).Import(ctx, doc, registry.Replace, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to import bob@2.0.0: boom")
+
+	plugins, err := l.List(ctx, providers.ListOpts{SortBy: providers.SortByName})
+	require.NoError(t, err)
+	var refs []string
+	for _, p := range plugins {
+		refs = append(refs, providers.Ref(p.Name, p.Version))
+	}
+	assert.Equal(t, []string{"bob@1.0.0", "bob@2.0.0", "carol@"}, refs)
+	assert.Equal(t, "skarlso/providers:echo-v1", plugins[0].Container.Image)
+	assert.True(t, plugins[0].Active)
+	trash, err := l.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+	after, err := l.AuditLog(ctx, providers.AuditOpts{})
+	require.NoError(t, err)
+	assert.Len(t, after, len(before))
+}
@@ -11,7 +11,9 @@ import (
 )
 
 var (
-	testDbLocation string
+	testDbLocation         string
+	testPostgresDSN        string
+	testPostgresSkipReason string
 )
 
 // TestMain runs the tests for the package and allows us to bring up any external dependencies required.
@@ -38,5 +40,13 @@ func testMain(m *testing.M) int {
 		}
 	}()
 
+	dsn, stop, err := startPostgres()
+	if err != nil {
+		testPostgresSkipReason = err.Error()
+	} else {
+		testPostgresDSN = dsn
+		defer stop()
+	}
+
 	return m.Run()
 }
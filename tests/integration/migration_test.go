@@ -0,0 +1,75 @@
+package livestore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+)
+
+func TestLiteStorer_MigratesExistingDatabase(t *testing.T) {
+	location := t.TempDir()
+	// a database created before migrations were introduced
+	db, err := sql.Open("sqlite3", filepath.Join(location, "provider.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`create table plugins (id integer primary key, name text unique, type text, location text, image text);`)
+	require.NoError(t, err)
+	_, err = db.Exec(`insert into plugins(name, type, location, image) values('bob', 'container', '', 'skarlso/providers:echo-v1');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	l, err := storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	require.NoError(t, err)
+	p, err := l.Get(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "skarlso/providers:echo-v1", p.Container.Image)
+	assert.True(t, p.CreatedAt.IsZero())
+	assert.Empty(t, p.Labels)
+	assert.Empty(t, p.Version)
+	assert.True(t, p.Active)
+
+	// new versions can be added next to the migrated plugin
+	bob := *p
+	bob.Version = "1.0.0"
+	require.NoError(t, l.Create(context.Background(), &bob))
+	p, err = l.Get(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Empty(t, p.Version)
+
+	// migrating again is a no-op
+	_, err = storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	assert.NoError(t, err)
+}
+
+func TestLiteStorer_SchemaVersionAndIntegrity(t *testing.T) {
+	ctx := context.Background()
+	location := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(location, "provider.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`create table plugins (id integer primary key, name text unique, type text, location text, image text);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// checking doesn't migrate
+	l := &storer.LiteStorer{Logger: zerolog.New(os.Stderr), DBLocation: location}
+	version, latest, err := l.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Greater(t, latest, 0)
+	problems, err := l.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	l, err = storer.NewLiteStorer(zerolog.New(os.Stderr), location)
+	require.NoError(t, err)
+	version, latest, err = l.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+}
@@ -27,6 +27,12 @@ func TestPluginStore_MainFlow(t *testing.T) {
 		},
 	})
 	assert.NoError(t, err)
+	// names are unique
+	err = l.Create(ctx, &models.Plugin{
+		Name: "test-bare-1",
+		Type: models.Bare,
+	})
+	assert.ErrorIs(t, err, providers.ErrAlreadyExists)
 	// create a bare metal plugin
 	err = l.Create(ctx, &models.Plugin{
 		Name: "test-container-1",
@@ -68,4 +74,5 @@ func TestPluginStore_MainFlow(t *testing.T) {
 	assert.NoError(t, err)
 	_, err = l.Get(ctx, "test-bare-1")
 	assert.EqualError(t, err, "failed to run get: sql: no rows in result set")
+	assert.ErrorIs(t, err, providers.ErrNotFound)
 }
@@ -0,0 +1,151 @@
+package livestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/providers-example/pkg/providers"
+	"github.com/Skarlso/providers-example/pkg/providers/storer"
+	"github.com/Skarlso/providers-example/pkg/providers/storertest"
+)
+
+// postgresDSNEnv can point the tests to an already running PostgreSQL instead of launching one.
+const postgresDSNEnv = "PROVIDERS_TEST_POSTGRES_DSN"
+
+var databaseCounter int32
+
+func TestPostgresStorer_Conformance(t *testing.T) {
+	storertest.Run(t, postgresFactory(t))
+}
+
+func TestPostgresStorer_TrashConformance(t *testing.T) {
+	factory := postgresFactory(t)
+	storertest.RunTrash(t, func(t *testing.T, retention time.Duration) providers.Storer {
+		p := factory(t).(*storer.PostgresStorer)
+		p.TrashRetention = retention
+		return p
+	})
+}
+
+func TestPostgresStorer_TransactionConformance(t *testing.T) {
+	storertest.RunTransaction(t, postgresFactory(t))
+}
+
+func TestPostgresStorer_PipelineConformance(t *testing.T) {
+	storertest.RunPipelines(t, postgresFactory(t))
+}
+
+// postgresFactory skips the test if there is no PostgreSQL, otherwise it returns a factory which gives
+// every test its own database, so they start out empty.
+func postgresFactory(t *testing.T) storertest.Factory {
+	if testPostgresDSN == "" {
+		t.Skipf("no postgres available: %s", testPostgresSkipReason)
+	}
+	admin, err := sql.Open("postgres", testPostgresDSN)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = admin.Close()
+	})
+
+	return func(t *testing.T) providers.Storer {
+		name := fmt.Sprintf("providers_test_%d", atomic.AddInt32(&databaseCounter, 1))
+		_, err := admin.Exec("create database " + name)
+		require.NoError(t, err)
+		p, err := storer.NewPostgresStorer(zerolog.New(os.Stderr), withDatabase(t, testPostgresDSN, name))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = p.Close()
+			_, _ = admin.Exec("drop database if exists " + name)
+		})
+		return p
+	}
+}
+
+// startPostgres launches a throwaway PostgreSQL server from the binaries found on the machine.
+// It returns the DSN to connect with and a function to stop the server.
+func startPostgres() (string, func(), error) {
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		return dsn, func() {}, nil
+	}
+	initdb, err := findPostgresBinary("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl, err := findPostgresBinary("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "postgres_test")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary folder: %w", err)
+	}
+	data := filepath.Join(dir, "data")
+	if output, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("failed to run initdb: %w, output: %s", err, output)
+	}
+	port, err := freePort()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	if output, err := exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(dir, "log"), "-w", "start").CombinedOutput(); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("failed to start postgres: %w, output: %s", err, output)
+	}
+	stop := func() {
+		if output, err := exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").CombinedOutput(); err != nil {
+			fmt.Printf("failed to stop postgres: %s, output: %s\n", err, output)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Println("failed to remove postgres folder: ", err)
+		}
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+// findPostgresBinary looks for a PostgreSQL binary in PATH and in the location used by Debian based distributions.
+func findPostgresBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	if len(matches) > 0 {
+		return matches[len(matches)-1], nil
+	}
+	return "", fmt.Errorf("%s not found, install PostgreSQL or set %s", name, postgresDSNEnv)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, errors.New("unexpected listener address")
+	}
+	return addr.Port, nil
+}
+
+// withDatabase returns the dsn pointing at a different database.
+func withDatabase(t *testing.T, dsn, database string) string {
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	u.Path = "/" + database
+	return u.String()
+}